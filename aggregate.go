@@ -0,0 +1,368 @@
+package crud
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/polaris0915/go-crud/cError"
+	"github.com/polaris0915/go-crud/model"
+	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// 聚合函数
+const (
+	aggCount = "count"
+	aggSum   = "sum"
+	aggAvg   = "avg"
+	aggMin   = "min"
+	aggMax   = "max"
+)
+
+// 时间字段分组的粒度
+const (
+	bucketDay   = "day"
+	bucketWeek  = "week"
+	bucketMonth = "month"
+)
+
+// aggregateQueryParams Aggregate 中有特殊含义的查询参数，不会被当作字段过滤条件
+var aggregateQueryParams = map[string]struct{}{
+	"group_by": empty, "metrics": empty, "having": empty, "sort": empty, "filter": empty,
+	"include_deleted": empty, "only_deleted": empty,
+}
+
+var (
+	int64Type   = reflect.TypeOf(int64(0))
+	float64Type = reflect.TypeOf(float64(0))
+)
+
+// aggregateGroup 分组字段，Bucket 为时间字段的分组粒度
+type aggregateGroup struct {
+	Field  string
+	Bucket string
+	// expr 分组使用的SQL表达式
+	expr string
+}
+
+// alias 分组在结果中的名称，按时间粒度分组时为 字段_粒度，例如 created_at_month
+func (g aggregateGroup) alias() string {
+	if g.Bucket != "" {
+		return g.Field + "_" + g.Bucket
+	}
+	return g.Field
+}
+
+// aggregateMetric 统计指标，count 的 Field 为空时统计记录数
+type aggregateMetric struct {
+	Func  string
+	Field string
+	// expr 统计使用的SQL表达式，valueType 统计结果的类型，用于 having 中值的转换
+	expr      string
+	valueType reflect.Type
+}
+
+// alias 指标在结果中的名称，例如 count、sum_file_size
+func (m aggregateMetric) alias() string {
+	if m.Field == "" {
+		return m.Func
+	}
+	return m.Func + "_" + m.Field
+}
+
+// parseAggregateGroups 解析分组参数，例如 file_type,created_at:month
+func parseAggregateGroups(param string) []aggregateGroup {
+	var groups []aggregateGroup
+	for _, item := range splitParam(param) {
+		field, bucket, _ := strings.Cut(item, ":")
+		groups = append(groups, aggregateGroup{Field: strings.TrimSpace(field), Bucket: strings.TrimSpace(bucket)})
+	}
+	return groups
+}
+
+// parseAggregateMetrics 解析统计指标参数，例如 count,sum:file_size，没有指定时统计记录数
+func parseAggregateMetrics(param string) []aggregateMetric {
+	var metrics []aggregateMetric
+	for _, item := range splitParam(param) {
+		fn, field, _ := strings.Cut(item, ":")
+		metrics = append(metrics, aggregateMetric{Func: strings.ToLower(strings.TrimSpace(fn)), Field: strings.TrimSpace(field)})
+	}
+	if len(metrics) == 0 {
+		metrics = append(metrics, aggregateMetric{Func: aggCount})
+	}
+	return metrics
+}
+
+// buildAggregate 检查分组字段和统计指标，并生成对应的SQL表达式
+// 字段只能是 allow_get 字段，sum、avg 只支持数值类型，min、max 只支持数值、时间以及字符串类型，时间粒度只支持时间类型
+func (r *RegisteredModel) buildAggregate(groups []aggregateGroup, metrics []aggregateMetric, dialect string) error {
+	aliases := make(map[string]struct{}, len(groups)+len(metrics))
+	checkAlias := func(alias string) error {
+		if _, ok := aliases[alias]; ok {
+			return fmt.Errorf("%s 重复", alias)
+		}
+		aliases[alias] = empty
+		return nil
+	}
+
+	for i := range groups {
+		g := &groups[i]
+		fieldType, err := r.aggregateFieldType(g.Field)
+		if err != nil {
+			return err
+		}
+		g.expr = g.Field
+		if g.Bucket != "" {
+			if !isTimeType(fieldType) {
+				return fmt.Errorf("字段 %s 不是时间类型，不支持按 %s 分组", g.Field, g.Bucket)
+			}
+			if g.expr, err = timeBucketExpr(dialect, g.Field, g.Bucket); err != nil {
+				return err
+			}
+		}
+		if err := checkAlias(g.alias()); err != nil {
+			return err
+		}
+	}
+
+	for i := range metrics {
+		m := &metrics[i]
+		if m.Field == "" {
+			if m.Func != aggCount {
+				return fmt.Errorf("统计指标 %s 需要指定字段，例如 %s:file_size", m.Func, m.Func)
+			}
+			m.expr, m.valueType = "COUNT(*)", int64Type
+		} else {
+			fieldType, err := r.aggregateFieldType(m.Field)
+			if err != nil {
+				return err
+			}
+			switch m.Func {
+			case aggCount:
+				m.valueType = int64Type
+			case aggSum, aggAvg:
+				if !isNumericKind(fieldType.Kind()) {
+					return fmt.Errorf("字段 %s 不是数值类型，不支持 %s", m.Field, m.Func)
+				}
+				m.valueType = float64Type
+			case aggMin, aggMax:
+				if !isOrderedType(fieldType) && fieldType.Kind() != reflect.String {
+					return fmt.Errorf("字段 %s 不支持 %s", m.Field, m.Func)
+				}
+				m.valueType = fieldType
+			default:
+				return fmt.Errorf("不支持的统计指标 %s，只支持 count、sum、avg、min、max", m.Func)
+			}
+			m.expr = fmt.Sprintf("%s(%s)", strings.ToUpper(m.Func), m.Field)
+		}
+		if err := checkAlias(m.alias()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// aggregateFieldType 检查字段是否可以用于聚合，并返回字段的类型
+func (r *RegisteredModel) aggregateFieldType(name string) (reflect.Type, error) {
+	if _, ok := r.AllowGetFields[name]; !ok {
+		return nil, fmt.Errorf("不允许按字段 %s 聚合", name)
+	}
+	field := r.fieldByJsonTag(name)
+	if field == nil {
+		return nil, fmt.Errorf("字段 %s 不存在", name)
+	}
+	return filterFieldType(field.Type), nil
+}
+
+// timeBucketExpr 生成将时间字段按粒度格式化的SQL表达式
+// 结果为字符串，例如 2026-01-05、2026-W01、2026-01，不同数据库周的计算方式略有差别
+func timeBucketExpr(dialect, column, bucket string) (string, error) {
+	formats := map[string]map[string]string{
+		"mysql": {
+			bucketDay:   "DATE_FORMAT(%s, '%%Y-%%m-%%d')",
+			bucketWeek:  "DATE_FORMAT(%s, '%%x-W%%v')",
+			bucketMonth: "DATE_FORMAT(%s, '%%Y-%%m')",
+		},
+		"postgres": {
+			bucketDay:   "to_char(%s, 'YYYY-MM-DD')",
+			bucketWeek:  "to_char(%s, 'IYYY-\"W\"IW')",
+			bucketMonth: "to_char(%s, 'YYYY-MM')",
+		},
+		"sqlite": {
+			bucketDay:   "strftime('%%Y-%%m-%%d', %s)",
+			bucketWeek:  "strftime('%%Y-W%%W', %s)",
+			bucketMonth: "strftime('%%Y-%%m', %s)",
+		},
+	}
+
+	dialectFormats, ok := formats[dialect]
+	if !ok {
+		return "", fmt.Errorf("当前数据库 %s 不支持按时间分组", dialect)
+	}
+	format, ok := dialectFormats[bucket]
+	if !ok {
+		return "", fmt.Errorf("不支持的时间粒度 %s，只支持 day、week、month", bucket)
+	}
+	return fmt.Sprintf(format, column), nil
+}
+
+// buildHaving 将 having 表达式编译为查询语句，只能使用统计指标的名称，例如 count>10;sum_file_size>=1024
+// 不是所有数据库都支持在 HAVING 中使用别名，这里替换为统计表达式
+func buildHaving(expr *filterExpr, metrics []aggregateMetric) (string, []interface{}, error) {
+	return buildExpr(expr, func(cond filterCondition) (string, []interface{}, error) {
+		i := slices.IndexFunc(metrics, func(m aggregateMetric) bool { return m.alias() == cond.Field })
+		if i < 0 {
+			return "", nil, fmt.Errorf("having 只能使用统计指标，%s 不是统计指标", cond.Field)
+		}
+		return buildColumnCondition(cond, metrics[i].expr, metrics[i].valueType)
+	})
+}
+
+// Aggregate 执行聚合查询，与 GetList 共用过滤条件
+// 例如 GET /file/aggregate?group_by=file_type&metrics=count,sum:file_size&having=count>10&sort=-count
+func (c *Core[T]) Aggregate() {
+	ctx := c.ginCtx
+
+	// 1. 获取模型元数据，嵌套路由检查父资源是否存在
+	modelMeta := getModelMeta(c.getModel().TableName())
+	if modelMeta == nil {
+		c.err = cError.New(cError.ErrReadGeneral, nil, errors.New("未找到模型元数据"))
+		return
+	}
+	if c.resolveParent(); c.err != nil {
+		return
+	}
+
+	// 2. 解析并检查分组字段和统计指标
+	db := model.Use()
+	groups := parseAggregateGroups(ctx.Query("group_by"))
+	metrics := parseAggregateMetrics(ctx.Query("metrics"))
+	if err := modelMeta.buildAggregate(groups, metrics, db.Dialector.Name()); err != nil {
+		c.err = cError.New(cError.ErrReadAggregate, err.Error(), err)
+		return
+	}
+
+	// 3. 解析排序参数，只能按分组或者统计指标排序，没有指定时按分组排序
+	aliases := make([]string, 0, len(groups)+len(metrics))
+	for _, g := range groups {
+		aliases = append(aliases, g.alias())
+	}
+	for _, m := range metrics {
+		aliases = append(aliases, m.alias())
+	}
+	sorts, err := parseSortParam(ctx.Query("sort"))
+	if err != nil {
+		c.err = cError.New(cError.ErrReadSort, err.Error(), err)
+		return
+	}
+	for _, s := range sorts {
+		if !slices.Contains(aliases, s.Field) {
+			c.err = cError.New(cError.ErrReadSort, nil, fmt.Errorf("只能按分组或者统计指标排序，%s 不是分组或者统计指标", s.Field))
+			return
+		}
+	}
+	if len(sorts) == 0 {
+		for _, g := range groups {
+			sorts = append(sorts, sortField{Field: g.alias()})
+		}
+	}
+
+	// 4. 解析过滤参数，与 GetList 相同
+	conditions, where := c.parseFilterParams(modelMeta, aggregateQueryParams)
+	if c.err != nil {
+		return
+	}
+	deleted := c.deletedQuery()
+	if c.err != nil {
+		return
+	}
+
+	// 5. 执行前置钩子
+	if c.beforeHook != nil {
+		if err := c.beforeHook(c); err != nil {
+			c.err = cError.New(cError.ErrReadHookFailure, nil, errors.New("聚合查询前置钩子执行失败"))
+			return
+		}
+	}
+
+	// 6. 处理过滤条件
+	db, _ = c.applyFilters(db.Table(c.getModel().TableName()), modelMeta, conditions, where)
+	if c.err != nil {
+		return
+	}
+	db = withDeleted(db, modelMeta, "", deleted)
+
+	// 7. 组织聚合查询
+	selects := make([]string, 0, len(groups)+len(metrics))
+	for _, g := range groups {
+		selects = append(selects, fmt.Sprintf("%s AS %s", g.expr, g.alias()))
+		db = db.Group(g.expr)
+	}
+	for _, m := range metrics {
+		selects = append(selects, fmt.Sprintf("%s AS %s", m.expr, m.alias()))
+	}
+	db = db.Select(selects)
+
+	if having := ctx.Query("having"); having != "" {
+		expr, err := parseFilterExpr(having)
+		if err != nil {
+			c.err = newFilterError(err)
+			return
+		}
+		query, args, err := buildHaving(expr, metrics)
+		if err != nil {
+			c.err = cError.New(cError.ErrReadFilter, err.Error(), err)
+			return
+		}
+		db = db.Having(query, args...)
+	}
+	for _, s := range sorts {
+		for _, clause := range s.orderClauses() {
+			db = db.Order(clause)
+		}
+	}
+
+	// 8. 查询结果
+	results := make([]map[string]interface{}, 0)
+	if err := db.Find(&results).Error; err != nil {
+		c.err = cError.New(cError.ErrDBQuery, nil, err)
+		return
+	}
+	normalizeAggregateResults(results, metrics)
+
+	// 9. 执行后置钩子
+	if c.afterHook != nil {
+		if err := c.afterHook(c); err != nil {
+			c.err = cError.New(cError.ErrReadHookFailure, nil, errors.New("聚合查询后置钩子执行失败"))
+			return
+		}
+	}
+
+	// 10. 返回结果
+	HandleRes(ctx, http.StatusOK, results, "")
+}
+
+// normalizeAggregateResults 部分数据库驱动会将 SUM、AVG 的结果以及字符串扫描为 []byte，直接序列化会变成 base64
+func normalizeAggregateResults(results []map[string]interface{}, metrics []aggregateMetric) {
+	numeric := make(map[string]struct{}, len(metrics))
+	for _, m := range metrics {
+		if m.valueType == int64Type || m.valueType == float64Type {
+			numeric[m.alias()] = empty
+		}
+	}
+	for _, row := range results {
+		for key, value := range row {
+			b, ok := value.([]byte)
+			if !ok {
+				continue
+			}
+			if _, ok := numeric[key]; ok {
+				row[key] = json.Number(b)
+			} else {
+				row[key] = string(b)
+			}
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package crud
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/polaris0915/go-crud/model"
+)
+
+func TestAggregate(t *testing.T) {
+	r, db := newTestServer(t, &model.RelateType{}, &model.File{})
+
+	for i, f := range []struct {
+		fileType  string
+		size      uint64
+		uploader  uint64
+		createdAt string
+	}{
+		{"image", 100, 1, "2026-01-05"},
+		{"image", 300, 2, "2026-01-20"},
+		{"doc", 50, 1, "2026-02-01"},
+		{"image", 200, 1, "2026-02-10"},
+		{"video", 1000, 2, "2026-03-01"},
+	} {
+		createdAt, _ := time.Parse(time.DateOnly, f.createdAt)
+		db.Create(&model.File{
+			ID:        uint64(i + 1),
+			FileName:  fmt.Sprintf("file%d", i+1),
+			FilePath:  fmt.Sprintf("/storage/file%d", i+1),
+			FileType:  f.fileType,
+			FileSize:  f.size,
+			Uploader:  f.uploader,
+			CreatedAt: createdAt,
+		})
+	}
+
+	RegisterModelApi[*model.File](r.Group("/api"), "file")
+
+	for _, tc := range []struct {
+		query string
+		want  string
+	}{
+		{
+			"group_by=file_type&metrics=count,sum:file_size,max:file_size&sort=-count,file_type",
+			`[{"count":3,"file_type":"image","max_file_size":300,"sum_file_size":600},` +
+				`{"count":1,"file_type":"doc","max_file_size":50,"sum_file_size":50},` +
+				`{"count":1,"file_type":"video","max_file_size":1000,"sum_file_size":1000}]`,
+		},
+		{
+			"group_by=file_type&metrics=count&having=count>1",
+			`[{"count":3,"file_type":"image"}]`,
+		},
+		{
+			// 与 GetList 相同的过滤参数
+			"group_by=file_type&metrics=count,avg:file_size&uploader=1&filter=file_size>=100",
+			`[{"avg_file_size":150,"count":2,"file_type":"image"}]`,
+		},
+		{
+			"group_by=created_at:month&having=count>=2",
+			`[{"count":2,"created_at_month":"2026-01"},{"count":2,"created_at_month":"2026-02"}]`,
+		},
+		{
+			"metrics=count,min:created_at",
+			`[{"count":5,"min_created_at":"2026-01-05 00:00:00+00:00"}]`,
+		},
+	} {
+		var res struct {
+			Data json.RawMessage `json:"data"`
+		}
+		doExpandRequest(t, r, "/api/file/aggregate?"+tc.query, &res)
+		if string(res.Data) != tc.want {
+			t.Errorf("%s:\ngot  %s\nwant %s", tc.query, res.Data, tc.want)
+		}
+	}
+
+	for _, query := range []string{
+		"group_by=deleted_at",
+		"group_by=file_type:month",
+		"group_by=created_at:year",
+		"metrics=sum:file_type",
+		"metrics=sum",
+		"metrics=median:file_size",
+		"metrics=count,count",
+		"group_by=file_type&sort=file_size",
+		"group_by=file_type&having=file_type==image",
+		"group_by=file_type&having=count>abc",
+	} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/file/aggregate?"+query, nil))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d, body %s", query, w.Code, w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package crud
+
+import (
+	"fmt"
+	"github.com/polaris0915/go-crud/cError"
+	"github.com/polaris0915/go-crud/model"
+	"gorm.io/gorm"
+	"net/http"
+	"reflect"
+)
+
+// maxBatchSize 批量创建一次最多创建的记录数
+const maxBatchSize = 1000
+
+// createBatchSize CreateInBatches 每次插入的记录数
+const createBatchSize = 100
+
+// batchItemResult 批量创建中单条记录的结果
+type batchItemResult struct {
+	// Index 记录在请求数组中的下标，从0开始
+	Index   int         `json:"index"`
+	Success bool        `json:"success"`
+	ID      interface{} `json:"id,omitempty"`
+	Code    int         `json:"code,omitempty"`
+	Message string      `json:"message,omitempty"`
+	Detail  interface{} `json:"detail,omitempty"`
+}
+
+// batchCreateResult 批量创建的结果
+type batchCreateResult struct {
+	Created int               `json:"created"`
+	Failed  int               `json:"failed"`
+	Items   []batchItemResult `json:"items"`
+}
+
+// fail 记录创建失败的原因
+func (r *batchItemResult) fail(err *cError.Error) {
+	r.Success, r.Code, r.Message, r.Detail = false, err.Code, err.Message, err.PublicDetail()
+}
+
+// CreateBatch 批量创建记录，请求体为 JSON 数组
+// 每条记录与 Create 使用相同的校验、唯一性检查、模型转换以及钩子，同一批次中唯一字段重复的记录也会创建失败
+// 校验通过的记录在同一个事务中使用 CreateInBatches 创建，插入或者后置钩子失败时全部回滚
+func (c *Core[T]) CreateBatch() {
+	// 1. 嵌套路由检查父资源是否存在
+	if c.resolveParent(); c.err != nil {
+		return
+	}
+
+	// 2. 绑定请求数据
+	var payloads []map[string]interface{}
+	if err := c.ginCtx.ShouldBindJSON(&payloads); err != nil {
+		c.err = cError.New(cError.ErrCreateBatch, "请求体必须是 JSON 数组", err)
+		return
+	}
+	if len(payloads) == 0 || len(payloads) > maxBatchSize {
+		err := fmt.Errorf("一次可以创建 1 到 %d 条记录", maxBatchSize)
+		c.err = cError.New(cError.ErrCreateBatch, err.Error(), err)
+		return
+	}
+
+	// 3. 逐条校验请求数据，检查唯一性约束并转换为模型
+	modelMeta := getModelMeta(c.getModel().TableName())
+	result := &batchCreateResult{Items: make([]batchItemResult, len(payloads))}
+	// seen 记录同一批次中已经出现的唯一字段的值，值为记录的下标
+	seen := make(map[string]map[string]int)
+	var items []*Core[T]
+	var indexes []int
+	for i, payload := range payloads {
+		result.Items[i].Index = i
+		item := c.itemCore(payload)
+		if item.validateCreate(); item.err == nil {
+			item.checkCreateUniqueness()
+		}
+		if item.err == nil {
+			item.decodeCreate()
+		}
+		// 只记录通过了转换以及前置钩子的记录的唯一字段，失败的记录不会与之后的记录冲突
+		if item.err == nil {
+			if index, ok := batchDuplicate(seen, modelMeta, item.payload, i); ok {
+				err := fmt.Errorf("与下标为 %d 的记录重复", index)
+				item.err = cError.New(cError.ErrCreateDuplicate, err.Error(), errDataDuplicated)
+			}
+		}
+		if item.err != nil {
+			result.Items[i].fail(item.err)
+			continue
+		}
+		items = append(items, item)
+		indexes = append(indexes, i)
+	}
+
+	// 4. 在同一个事务中创建所有校验通过的记录，并逐条执行创建后置钩子
+	if len(items) > 0 {
+		var failed *Core[T]
+		err := model.Use().Transaction(func(tx *gorm.DB) error {
+			models := make([]T, len(items))
+			for i, item := range items {
+				models[i] = item.model
+			}
+			if err := tx.CreateInBatches(models, createBatchSize).Error; err != nil {
+				return err
+			}
+			for _, item := range items {
+				if item.afterCreate(); item.err != nil {
+					failed = item
+					return item.err
+				}
+			}
+			return nil
+		})
+
+		for i, item := range items {
+			r := &result.Items[indexes[i]]
+			switch {
+			case failed == item:
+				r.fail(item.err)
+			case err != nil:
+				r.fail(cError.NewWithMessage(cError.ErrCreateGeneral, "同一批次的记录创建失败，已回滚", nil, err))
+			default:
+				r.Success, r.ID = true, modelID(item.model)
+			}
+		}
+	}
+
+	for _, item := range result.Items {
+		if item.Success {
+			result.Created++
+		} else {
+			result.Failed++
+		}
+	}
+	if result.Created > 0 {
+		invalidateModel(c.getModel().TableName())
+	}
+
+	// 5. 返回每条记录的结果，有记录失败时状态码为 200
+	status := http.StatusCreated
+	if result.Failed > 0 {
+		status = http.StatusOK
+	}
+	HandleRes(c.ginCtx, status, result, "")
+}
+
+// batchDuplicate 检查记录中的唯一字段是否与同一批次中之前的记录重复，重复时返回之前记录的下标
+// 不重复时将记录的唯一字段的值记录到 seen 中
+func batchDuplicate(
+	seen map[string]map[string]int, modelMeta *RegisteredModel, payload map[string]interface{}, index int,
+) (int, bool) {
+	values := make(map[string]string)
+	for _, field := range modelMeta.Fields {
+		if !field.Unique {
+			continue
+		}
+		val, ok := payload[field.JsonTag]
+		if !ok || val == nil {
+			continue
+		}
+		value := fmt.Sprint(val)
+		if i, ok := seen[field.JsonTag][value]; ok {
+			return i, true
+		}
+		values[field.JsonTag] = value
+	}
+	for field, value := range values {
+		if seen[field] == nil {
+			seen[field] = make(map[string]int)
+		}
+		seen[field][value] = index
+	}
+	return 0, false
+}
+
+// modelID 获取模型的ID，模型没有 ID 字段时返回空
+func modelID(m CModel) interface{} {
+	value := reflect.Indirect(reflect.ValueOf(m))
+	if value.Kind() != reflect.Struct {
+		return nil
+	}
+	if id := value.FieldByName("ID"); id.IsValid() {
+		return id.Interface()
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package crud
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/polaris0915/go-crud/cError"
+	"gorm.io/gorm"
+)
+
+func TestCreateBatch(t *testing.T) {
+	r, db := newTestServer(t, &importUser{})
+	db.Create(&importUser{Email: "a@x.com", Name: "A"})
+	queries := countQueries(t, db)
+	var inserts int
+	if err := db.Callback().Create().After("gorm:create").Register("test:count_inserts", func(tx *gorm.DB) {
+		inserts++
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// 前置钩子拒绝名称为 blocked 的记录，后置钩子在名称为 fail 时失败，用于检查整批回滚
+	var hooked int
+	RegisterModelApi[*importUser](r.Group("/api"), "user",
+		BeforeCreate(func(core ICore) error {
+			if core.GetPayload()["name"] == "blocked" {
+				return errors.New("blocked")
+			}
+			return nil
+		}),
+		AfterCreate(func(core ICore) error {
+			hooked++
+			if core.GetModel().(*importUser).Name == "fail" {
+				return errors.New("fail")
+			}
+			return nil
+		}),
+	)
+
+	type response struct {
+		Code int               `json:"code"`
+		Data batchCreateResult `json:"data"`
+	}
+	doBatch := func(t *testing.T, body string) (int, batchCreateResult) {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/api/user/batch", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		var resp response
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("body = %s: %v", w.Body.String(), err)
+		}
+		return w.Code, resp.Data
+	}
+	count := func() int64 {
+		var n int64
+		db.Model(&importUser{}).Count(&n)
+		return n
+	}
+
+	t.Run("partial", func(t *testing.T) {
+		// 第1条与已有记录重复，第2条缺少必填字段，第4条与第0条重复
+		status, result := doBatch(t, `[{"email":"b@x.com","name":"B"},{"email":"a@x.com","name":"A2"},`+
+			`{"email":"c@x.com"},{"email":"d@x.com","name":"D"},{"email":"b@x.com","name":"B2"}]`)
+		if status != http.StatusOK || result.Created != 2 || result.Failed != 3 {
+			t.Fatalf("status = %d, result = %+v", status, result)
+		}
+		codes := []int{0, cError.ErrCreateDuplicate, cError.ErrCreateMissingField, 0, cError.ErrCreateDuplicate}
+		for i, item := range result.Items {
+			if item.Index != i || item.Code != codes[i] || item.Success != (codes[i] == 0) {
+				t.Errorf("item %d = %+v", i, item)
+			}
+		}
+		if result.Items[0].ID == nil || result.Items[3].ID == nil {
+			t.Errorf("missing ids: %+v", result.Items)
+		}
+		if hooked != 2 || count() != 3 {
+			t.Errorf("hooked = %d, count = %d", hooked, count())
+		}
+	})
+
+	t.Run("single insert", func(t *testing.T) {
+		*queries, inserts = 0, 0
+		var items []string
+		for i := 0; i < 5; i++ {
+			items = append(items, fmt.Sprintf(`{"email":"batch%d@x.com","name":"N%d"}`, i, i))
+		}
+		status, result := doBatch(t, "["+strings.Join(items, ",")+"]")
+		if status != http.StatusCreated || result.Created != 5 {
+			t.Fatalf("status = %d, result = %+v", status, result)
+		}
+		// 每条记录检查一次唯一性，所有记录一次插入
+		if *queries != 5 || inserts != 1 {
+			t.Errorf("queries = %d, inserts = %d, want 5 and 1", *queries, inserts)
+		}
+	})
+
+	t.Run("rollback", func(t *testing.T) {
+		before := count()
+		status, result := doBatch(t, `[{"email":"e@x.com","name":"E"},{"email":"f@x.com","name":"fail"}]`)
+		if status != http.StatusOK || result.Created != 0 || result.Failed != 2 {
+			t.Fatalf("status = %d, result = %+v", status, result)
+		}
+		if result.Items[0].Code != cError.ErrCreateGeneral || result.Items[1].Code != cError.ErrCreateHookFailure {
+			t.Errorf("items = %+v", result.Items)
+		}
+		if count() != before {
+			t.Errorf("count = %d, want %d", count(), before)
+		}
+	})
+
+	t.Run("failed item", func(t *testing.T) {
+		// 第0条被前置钩子拒绝，不占用唯一字段的值，第1条可以创建
+		status, result := doBatch(t, `[{"email":"h@x.com","name":"blocked"},{"email":"h@x.com","name":"H"}]`)
+		if status != http.StatusOK || result.Created != 1 || result.Failed != 1 {
+			t.Fatalf("status = %d, result = %+v", status, result)
+		}
+		if result.Items[0].Code != cError.ErrCreateHookFailure || !result.Items[1].Success {
+			t.Errorf("items = %+v", result.Items)
+		}
+	})
+
+	for _, body := range []string{`{"email":"g@x.com","name":"G"}`, `[]`} {
+		if status, _ := doBatch(t, body); status != http.StatusBadRequest {
+			t.Errorf("%s: status = %d", body, status)
+		}
+	}
+}
@@ -0,0 +1,280 @@
+package crud
+
+import (
+	"errors"
+	"fmt"
+	"github.com/polaris0915/go-crud/cError"
+	"github.com/polaris0915/go-crud/model"
+	"github.com/spf13/cast"
+	"gorm.io/gorm"
+	"io"
+	"net/http"
+)
+
+// defaultMaxAffected 批量更新、批量删除一次最多影响的记录数的默认值
+const defaultMaxAffected = 1000
+
+// bulkQueryParams 批量更新、批量删除中有特殊含义的查询参数，不会被当作字段过滤条件
+var bulkQueryParams = map[string]struct{}{
+	"ids": empty, "dry_run": empty, "max_affected": empty, "filter": empty,
+}
+
+// bulkRequest 批量更新、批量删除的请求体
+type bulkRequest struct {
+	// IDs 需要操作的记录ID，没有时使用URL中的 ids 参数，与过滤参数之间为 AND 关系
+	IDs []uint64 `json:"ids"`
+	// Data 批量更新的字段，只能是允许部分更新的字段
+	Data map[string]interface{} `json:"data"`
+}
+
+// bulkResult 批量更新、批量删除的结果
+type bulkResult struct {
+	// Matched 匹配的记录数
+	Matched int64 `json:"matched"`
+	// Affected 实际更新或者删除的记录数
+	Affected int64 `json:"affected"`
+	// IDs 匹配的记录ID，dry_run 时为空
+	IDs    []uint64 `json:"ids,omitempty"`
+	DryRun bool     `json:"dry_run"`
+}
+
+// bulkCodes 批量更新、批量删除中没有指定条件以及记录数超过限制时的错误码
+type bulkCodes struct {
+	missing int
+	tooMany int
+}
+
+var (
+	bulkUpdateCodes = bulkCodes{missing: cError.ErrUpdateMissingField, tooMany: cError.ErrUpdateTooMany}
+	bulkDeleteCodes = bulkCodes{missing: cError.ErrDeleteMissingField, tooMany: cError.ErrDeleteTooMany}
+)
+
+// BulkUpdate 批量更新，更新的记录由 ids 或者与 GetList 相同的过滤参数指定
+// 请求体例如 {"ids": [1, 2], "data": {"display_name": "x"}}，钩子函数中可以通过 GetAffectedIDs 获取更新的记录ID
+func (c *Core[T]) BulkUpdate() {
+	// 1. 嵌套路由检查父资源是否存在
+	if c.resolveParent(); c.err != nil {
+		return
+	}
+
+	// 2. 绑定请求数据，检查更新的字段是否都是允许部分更新的字段
+	var req bulkRequest
+	if err := c.ginCtx.ShouldBindJSON(&req); err != nil {
+		c.err = cError.New(cError.ErrUpdateInvalidField, nil, errors.New("无效的请求数据格式"))
+		return
+	}
+	if c.checkPartialUpdate(getModelMeta(c.getModel().TableName()), req.Data); c.err != nil {
+		return
+	}
+	// 嵌套路由中子资源不能修改为其他父资源
+	c.injectScope(req.Data)
+	c.payload = req.Data
+
+	// 3. 查询匹配的记录
+	result, filters := c.bulkTargets(req.IDs, bulkUpdateCodes)
+	if c.err != nil {
+		return
+	}
+	if result.DryRun || result.Matched == 0 {
+		HandleRes(c.ginCtx, http.StatusOK, result, "")
+		return
+	}
+
+	// 4. 执行前置钩子
+	if c.beforeHook != nil {
+		if err := c.beforeHook(c); err != nil {
+			c.err = cError.New(cError.ErrUpdateHookFailure, nil, errors.New("更新前置钩子函数执行失败"))
+			return
+		}
+	}
+
+	// 5. 在事务中更新匹配的记录并执行后置钩子，后置钩子失败时回滚
+	// 更新时重新使用过滤条件，查询之后不再匹配的记录不会被更新
+	// 带有 version 标签的版本字段加1，之前获取的 ETag 失效
+	bumpVersion(req.Data, getModelMeta(c.getModel().TableName()))
+	err := model.Use().Transaction(func(tx *gorm.DB) error {
+		updated := tx.Model(c.getModel()).Scopes(filters).Where("id IN ?", c.affectedIDs).Updates(req.Data)
+		if updated.Error != nil {
+			c.err = cError.New(cError.ErrUpdateGeneral, nil, updated.Error)
+			return updated.Error
+		}
+		result.Affected = updated.RowsAffected
+		if c.afterHook != nil {
+			if err := c.afterHook(c); err != nil {
+				c.err = cError.New(cError.ErrUpdateHookFailure, nil, errors.New("更新后置钩子函数执行失败"))
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		if c.err == nil {
+			c.err = cError.New(cError.ErrDBTransaction, nil, err)
+		}
+		return
+	}
+	invalidateModel(c.getModel().TableName())
+
+	// 6. 返回结果
+	HandleRes(c.ginCtx, http.StatusOK, result, "")
+}
+
+// BulkDelete 批量删除，删除的记录由 ids 或者与 GetList 相同的过滤参数指定
+// 请求体可以为空，例如 DELETE /file?ids=1,2,3，钩子函数中可以通过 GetAffectedIDs 获取删除的记录ID
+func (c *Core[T]) BulkDelete() {
+	// 1. 嵌套路由检查父资源是否存在
+	if c.resolveParent(); c.err != nil {
+		return
+	}
+
+	// 2. 绑定请求数据，请求体可以为空
+	var req bulkRequest
+	if err := c.ginCtx.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.err = cError.New(cError.ErrInvalidRequest, nil, errors.New("无效的请求数据格式"))
+		return
+	}
+
+	// 3. 查询匹配的记录
+	result, filters := c.bulkTargets(req.IDs, bulkDeleteCodes)
+	if c.err != nil {
+		return
+	}
+	if result.DryRun || result.Matched == 0 {
+		HandleRes(c.ginCtx, http.StatusOK, result, "")
+		return
+	}
+
+	// 4. 执行前置钩子
+	if c.beforeHook != nil {
+		if err := c.beforeHook(c); err != nil {
+			c.err = cError.New(cError.ErrDeleteHookFailure, nil, errors.New("删除前置钩子函数执行失败"))
+			return
+		}
+	}
+
+	// 5. 在事务中删除匹配的记录并执行后置钩子，后置钩子失败时回滚
+	// 删除时重新使用过滤条件，查询之后不再匹配的记录不会被删除
+	err := model.Use().Transaction(func(tx *gorm.DB) error {
+		deleted := tx.Scopes(filters).Where("id IN ?", c.affectedIDs).Delete(c.getModel())
+		if deleted.Error != nil {
+			c.err = cError.New(cError.ErrDeleteGeneral, nil, deleted.Error)
+			return deleted.Error
+		}
+		result.Affected = deleted.RowsAffected
+		if c.afterHook != nil {
+			if err := c.afterHook(c); err != nil {
+				c.err = cError.New(cError.ErrDeleteHookFailure, nil, errors.New("删除后置钩子函数执行失败"))
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		if c.err == nil {
+			c.err = cError.New(cError.ErrDBTransaction, nil, err)
+		}
+		return
+	}
+	invalidateModel(c.getModel().TableName())
+
+	// 6. 返回结果
+	HandleRes(c.ginCtx, http.StatusOK, result, "")
+}
+
+// bulkTargets 查询批量操作匹配的记录ID，并记录到 affectedIDs 中，filters 用于在写入时重新使用相同的过滤条件
+// 没有指定 ids 以及过滤条件、匹配的记录数超过限制时返回错误，dry_run 时只统计匹配的记录数
+func (c *Core[T]) bulkTargets(ids []uint64, codes bulkCodes) (result *bulkResult, filters func(*gorm.DB) *gorm.DB) {
+	ctx := c.ginCtx
+	modelMeta := getModelMeta(c.getModel().TableName())
+
+	// 1. 请求体中没有 ids 时使用URL中的 ids 参数，例如 ids=1,2,3
+	if len(ids) == 0 {
+		if ids = c.queryIDs(); c.err != nil {
+			return nil, nil
+		}
+	}
+
+	// 2. 解析过滤参数，没有任何条件时不允许操作整张表
+	conditions, where := c.parseFilterParams(modelMeta, bulkQueryParams)
+	if c.err != nil {
+		return nil, nil
+	}
+	if len(ids) == 0 && len(conditions) == 0 && where == nil {
+		c.err = cError.New(codes.missing, "必须指定 ids 或者过滤条件", errors.New("批量操作缺少条件"))
+		return nil, nil
+	}
+
+	// 3. 一次最多影响的记录数，max_affected 参数只能比配置的值小
+	limit := c.maxAffected()
+	if param := ctx.Query("max_affected"); param != "" {
+		n, err := cast.ToIntE(param)
+		if err != nil || n < 1 {
+			c.err = cError.New(cError.ErrInvalidRequest, "max_affected 必须是正整数", err)
+			return nil, nil
+		}
+		limit = min(limit, n)
+	}
+
+	// 4. 查询匹配的记录，写入时重新使用相同的过滤条件
+	filters = func(db *gorm.DB) *gorm.DB {
+		db, _ = c.applyFilters(db, modelMeta, conditions, where)
+		if len(ids) > 0 {
+			db = db.Where("id IN ?", ids)
+		}
+		return db
+	}
+	db := filters(model.Use().Model(c.getModel()))
+	if c.err != nil {
+		return nil, nil
+	}
+	result = &bulkResult{DryRun: cast.ToBool(ctx.Query("dry_run"))}
+	if result.DryRun {
+		// dry_run 同样检查记录数是否超过限制，与实际执行的结果一致
+		if err := db.Count(&result.Matched).Error; err != nil {
+			c.err = cError.New(cError.ErrDBQuery, nil, err)
+			return nil, nil
+		}
+		if result.Matched > int64(limit) {
+			err := fmt.Errorf("匹配的记录数超过 %d", limit)
+			c.err = cError.New(codes.tooMany, err.Error(), err)
+			return nil, nil
+		}
+		return result, filters
+	}
+
+	// 多查询一条记录用于判断是否超过限制
+	var matched []uint64
+	if err := db.Order("id").Limit(limit+1).Pluck("id", &matched).Error; err != nil {
+		c.err = cError.New(cError.ErrDBQuery, nil, err)
+		return nil, nil
+	}
+	if len(matched) > limit {
+		err := fmt.Errorf("匹配的记录数超过 %d", limit)
+		c.err = cError.New(codes.tooMany, err.Error(), err)
+		return nil, nil
+	}
+	result.Matched, result.IDs = int64(len(matched)), matched
+	c.affectedIDs = matched
+	return result, filters
+}
+
+// queryIDs 解析URL中的 ids 参数，例如 ids=1,2,3
+func (c *Core[T]) queryIDs() (ids []uint64) {
+	for _, item := range splitParam(c.ginCtx.Query("ids")) {
+		id, err := cast.ToUint64E(item)
+		if err != nil || id == 0 {
+			c.err = cError.New(cError.ErrInvalidRequest, fmt.Sprintf("无效的ID: %s", item), err)
+			return nil
+		}
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// maxAffected 批量更新、批量删除一次最多影响的记录数
+func (c *Core[T]) maxAffected() int {
+	if c.config != nil && c.config.MaxAffected > 0 {
+		return c.config.MaxAffected
+	}
+	return defaultMaxAffected
+}
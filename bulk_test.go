@@ -0,0 +1,163 @@
+package crud
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/polaris0915/go-crud/cError"
+	"github.com/polaris0915/go-crud/model"
+)
+
+func TestBulkUpdateDelete(t *testing.T) {
+	r, db := newTestServer(t, &model.RelateType{}, &model.File{})
+	for i := 1; i <= 6; i++ {
+		fileType := "image"
+		if i > 4 {
+			fileType = "doc"
+		}
+		db.Create(&model.File{
+			ID:       uint64(i),
+			FileName: fmt.Sprintf("file%d", i),
+			FilePath: fmt.Sprintf("/storage/file%d", i),
+			FileType: fileType,
+		})
+	}
+
+	// 钩子函数中记录受影响的记录ID
+	var beforeIDs, afterIDs []uint64
+	record := func(ids *[]uint64) HookFunc {
+		return func(core ICore) error {
+			*ids = core.GetAffectedIDs()
+			return nil
+		}
+	}
+	// between 在查询匹配的记录之后、写入之前执行，用于模拟并发的修改
+	var between func()
+	before := func(core ICore) error {
+		if between != nil {
+			between()
+		}
+		return record(&beforeIDs)(core)
+	}
+	RegisterModelApi[*model.File](r.Group("/api"), "file",
+		BeforeUpdate(before), AfterUpdate(record(&afterIDs)),
+		BeforeDelete(before), AfterDelete(record(&afterIDs)),
+		MaxAffected(3),
+	)
+
+	type response struct {
+		Code int        `json:"code"`
+		Data bulkResult `json:"data"`
+	}
+	do := func(t *testing.T, method, url, body string) (int, response) {
+		t.Helper()
+		req := httptest.NewRequest(method, url, strings.NewReader(body))
+		if body != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		var resp response
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("body = %s: %v", w.Body.String(), err)
+		}
+		return w.Code, resp
+	}
+	fileTypes := func() map[uint64]string {
+		var files []model.File
+		db.Order("id").Find(&files)
+		result := make(map[uint64]string)
+		for _, f := range files {
+			result[f.ID] = f.FileType
+		}
+		return result
+	}
+
+	t.Run("update by ids", func(t *testing.T) {
+		status, resp := do(t, http.MethodPatch, "/api/file", `{"ids":[1,2],"data":{"file_type":"video"}}`)
+		if status != http.StatusOK || resp.Data.Matched != 2 || resp.Data.Affected != 2 {
+			t.Fatalf("status = %d, resp = %+v", status, resp)
+		}
+		if want := []uint64{1, 2}; !reflect.DeepEqual(beforeIDs, want) || !reflect.DeepEqual(afterIDs, want) {
+			t.Errorf("hook ids = %v, %v", beforeIDs, afterIDs)
+		}
+		if types := fileTypes(); types[1] != "video" || types[2] != "video" || types[3] != "image" {
+			t.Errorf("file types = %v", types)
+		}
+	})
+
+	t.Run("update by filter", func(t *testing.T) {
+		status, resp := do(t, http.MethodPatch, "/api/file?file_type=doc", `{"data":{"file_type":"archive"}}`)
+		if status != http.StatusOK || !reflect.DeepEqual(resp.Data.IDs, []uint64{5, 6}) {
+			t.Fatalf("status = %d, resp = %+v", status, resp)
+		}
+		if types := fileTypes(); types[5] != "archive" || types[6] != "archive" {
+			t.Errorf("file types = %v", types)
+		}
+	})
+
+	t.Run("rejected", func(t *testing.T) {
+		for _, tc := range []struct {
+			method, url, body string
+			code              int
+		}{
+			{http.MethodPatch, "/api/file", `{"ids":[1],"data":{"file_size":1}}`, cError.ErrUpdateInvalidField},
+			{http.MethodPatch, "/api/file", `{"data":{"file_type":"x"}}`, cError.ErrUpdateMissingField},
+			{http.MethodPatch, "/api/file?filter=file_size>=0", `{"data":{"file_type":"x"}}`, cError.ErrUpdateTooMany},
+			{http.MethodDelete, "/api/file?ids=1,2,3&max_affected=2", "", cError.ErrDeleteTooMany},
+			{http.MethodDelete, "/api/file?filter=file_size>=0&dry_run=true", "", cError.ErrDeleteTooMany},
+			{http.MethodDelete, "/api/file", "", cError.ErrDeleteMissingField},
+		} {
+			if status, resp := do(t, tc.method, tc.url, tc.body); status != http.StatusBadRequest || resp.Code != tc.code {
+				t.Errorf("%s %s: status = %d, code = %d, want %d", tc.method, tc.url, status, resp.Code, tc.code)
+			}
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		beforeIDs, afterIDs = nil, nil
+		status, resp := do(t, http.MethodDelete, "/api/file?file_type=image&dry_run=true", "")
+		if status != http.StatusOK || !resp.Data.DryRun || resp.Data.Matched != 2 || beforeIDs != nil {
+			t.Fatalf("dry run: status = %d, resp = %+v", status, resp)
+		}
+		if len(fileTypes()) != 6 {
+			t.Fatalf("dry run deleted files")
+		}
+
+		status, resp = do(t, http.MethodDelete, "/api/file?file_type=image", `{"ids":[3,4,5]}`)
+		if status != http.StatusOK || resp.Data.Affected != 2 || !reflect.DeepEqual(afterIDs, []uint64{3, 4}) {
+			t.Fatalf("status = %d, resp = %+v, ids = %v", status, resp, afterIDs)
+		}
+		if types := fileTypes(); len(types) != 4 || types[3] != "" || types[4] != "" {
+			t.Errorf("file types = %v", types)
+		}
+	})
+	t.Run("changed after match", func(t *testing.T) {
+		// 查询之后不再匹配过滤条件的记录不会被更新或者删除
+		between = func() { db.Model(&model.File{}).Where("id = ?", 6).Update("file_type", "doc") }
+		status, resp := do(t, http.MethodPatch, "/api/file?file_type=archive", `{"data":{"file_name":"renamed"}}`)
+		if status != http.StatusOK || resp.Data.Matched != 2 || resp.Data.Affected != 1 {
+			t.Fatalf("update: status = %d, resp = %+v", status, resp)
+		}
+		var file model.File
+		db.First(&file, 6)
+		if file.FileName != "file6" {
+			t.Errorf("file 6 renamed after it stopped matching")
+		}
+
+		between = func() { db.Model(&model.File{}).Where("id = ?", 2).Update("file_type", "doc") }
+		status, resp = do(t, http.MethodDelete, "/api/file?file_type=video", "")
+		between = nil
+		if status != http.StatusOK || resp.Data.Matched != 2 || resp.Data.Affected != 1 {
+			t.Fatalf("delete: status = %d, resp = %+v", status, resp)
+		}
+		if types := fileTypes(); types[1] != "" || types[2] != "doc" {
+			t.Errorf("file types = %v", types)
+		}
+	})
+}
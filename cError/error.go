@@ -46,6 +46,15 @@ func New(code int, details interface{}, internalErr error) *Error {
 	}
 }
 
+// PublicDetail 返回可以发送给客户端的错误详情
+// 只返回客户端错误（4xx）的详情，服务端错误的详情可能包含数据库驱动返回的内部错误信息，不对外暴露
+func (e *Error) PublicDetail() interface{} {
+	if e.HttpStatus >= http.StatusInternalServerError {
+		return nil
+	}
+	return e.Detail
+}
+
 // NewWithMessage 创建一个带自定义消息的应用错误
 func NewWithMessage(code int, message string, details interface{}, internalErr error) *Error {
 	info, exists := errorMap[code]
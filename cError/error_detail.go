@@ -11,6 +11,7 @@ const (
 	ErrTimeout         = 1004 // 操作超时
 	ErrTooManyRequests = 1005 // 请求过多
 	ErrInvalidConfig   = 1006 // 无效配置
+	ErrPrecondition    = 1007 // 缺少前置条件
 )
 
 // 数据库错误
@@ -33,6 +34,8 @@ const (
 	ErrCreateInvalidField = 3004 // 创建字段无效
 	ErrCreateRelation     = 3005 // 创建关联错误
 	ErrCreateHookFailure  = 3006 // 创建钩子函数失败
+	ErrCreateImport       = 3007 // 导入参数错误
+	ErrCreateBatch        = 3008 // 批量创建参数错误
 )
 
 // 读取操作错误
@@ -48,6 +51,9 @@ const (
 	ErrReadHookFailure  = 4008 // 读取钩子函数失败
 	ErrReadMissingField = 4009 // 读取缺少必填字段
 	ErrReadInvalidField = 4010 // 读取无效字段
+	ErrReadExpand       = 4011 // 关联展开参数错误
+	ErrReadAggregate    = 4012 // 聚合参数错误
+	ErrReadExport       = 4013 // 导出参数错误
 )
 
 // 更新操作错误
@@ -61,6 +67,7 @@ const (
 	ErrUpdateConcurrency  = 5006 // 并发更新错误
 	ErrUpdateHookFailure  = 5007 // 更新钩子函数失败
 	ErrUpdateMissingField = 5008 // 更新缺少必填字段
+	ErrUpdateTooMany      = 5009 // 更新的记录数超过限制
 )
 
 // 删除操作错误
@@ -73,6 +80,7 @@ const (
 	ErrDeleteProtected    = 6005 // 受保护记录不能删除
 	ErrDeleteHookFailure  = 6006 // 删除钩子函数失败
 	ErrDeleteMissingField = 6007 // 删除缺少必填字段
+	ErrDeleteTooMany      = 6008 // 删除的记录数超过限制
 )
 
 // 验证错误
@@ -109,6 +117,7 @@ var errorMap = map[int]struct {
 	ErrTimeout:         {"操作超时", http.StatusGatewayTimeout},
 	ErrTooManyRequests: {"请求过多", http.StatusTooManyRequests},
 	ErrInvalidConfig:   {"无效配置", http.StatusInternalServerError},
+	ErrPrecondition:    {"缺少 If-Match 请求头", http.StatusPreconditionRequired},
 
 	// 数据库错误
 	ErrDBConnection:  {"数据库连接错误", http.StatusInternalServerError},
@@ -127,6 +136,8 @@ var errorMap = map[int]struct {
 	ErrCreateInvalidField: {"字段值无效", http.StatusBadRequest},
 	ErrCreateRelation:     {"关联创建失败", http.StatusBadRequest},
 	ErrCreateHookFailure:  {"创建钩子执行失败", http.StatusInternalServerError},
+	ErrCreateImport:       {"无效的导入参数", http.StatusBadRequest},
+	ErrCreateBatch:        {"无效的批量创建参数", http.StatusBadRequest},
 
 	// 读取操作错误
 	ErrReadGeneral:      {"读取资源失败", http.StatusInternalServerError},
@@ -140,6 +151,9 @@ var errorMap = map[int]struct {
 	ErrReadHookFailure:  {"读取钩子执行失败", http.StatusInternalServerError},
 	ErrReadMissingField: {"读取缺少必填字段", http.StatusBadRequest},
 	ErrReadInvalidField: {"读取无效字段", http.StatusBadRequest},
+	ErrReadExpand:       {"无效的关联展开参数", http.StatusBadRequest},
+	ErrReadAggregate:    {"无效的聚合参数", http.StatusBadRequest},
+	ErrReadExport:       {"无效的导出参数", http.StatusBadRequest},
 
 	// 更新操作错误
 	ErrUpdateGeneral:      {"更新资源失败", http.StatusInternalServerError},
@@ -151,6 +165,7 @@ var errorMap = map[int]struct {
 	ErrUpdateConcurrency:  {"并发更新冲突", http.StatusConflict},
 	ErrUpdateHookFailure:  {"更新钩子执行失败", http.StatusInternalServerError},
 	ErrUpdateMissingField: {"缺少必填字段", http.StatusBadRequest},
+	ErrUpdateTooMany:      {"更新的记录数超过限制", http.StatusBadRequest},
 
 	// 删除操作错误
 	ErrDeleteGeneral:      {"删除资源失败", http.StatusInternalServerError},
@@ -161,6 +176,7 @@ var errorMap = map[int]struct {
 	ErrDeleteProtected:    {"资源受保护，不能删除", http.StatusForbidden},
 	ErrDeleteHookFailure:  {"删除钩子执行失败", http.StatusInternalServerError},
 	ErrDeleteMissingField: {"缺少必填字段", http.StatusBadRequest},
+	ErrDeleteTooMany:      {"删除的记录数超过限制", http.StatusBadRequest},
 
 	// 验证错误
 	ErrValidationGeneral:   {"验证失败", http.StatusBadRequest},
@@ -0,0 +1,231 @@
+package crud
+
+import (
+	"container/list"
+	"encoding/json"
+	"fmt"
+	"github.com/spf13/cast"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Cache Get 以及 GetList 的响应缓存
+// 模型发生创建、更新、删除时通过 Incr 增加模型的缓存代数使之前的缓存失效，因此不需要按前缀删除
+type Cache interface {
+	// Get 获取缓存，不存在或者已经过期时返回 false
+	Get(key string) ([]byte, bool, error)
+	// Set 设置缓存，ttl 为0时不过期
+	Set(key string, value []byte, ttl time.Duration) error
+	// Incr 将键的整数值加1并返回加1之后的值，键不存在时从0开始，不过期
+	Incr(key string) (int64, error)
+}
+
+// responseCaches 开启了响应缓存的模型使用的缓存，键为模型的表名
+var responseCaches = struct {
+	sync.RWMutex
+	caches map[string][]Cache
+}{caches: make(map[string][]Cache)}
+
+// registerResponseCache 记录模型使用的缓存，用于模型以及关联模型发生变化时使缓存失效
+func registerResponseCache(table string, cache Cache) {
+	responseCaches.Lock()
+	defer responseCaches.Unlock()
+
+	for _, registered := range responseCaches.caches[table] {
+		if registered == cache {
+			return
+		}
+	}
+	responseCaches.caches[table] = append(responseCaches.caches[table], cache)
+}
+
+// invalidateModel 模型发生创建、更新、删除之后，清空总记录数缓存，并使该模型以及关联了该模型的模型的响应缓存失效
+func invalidateModel(table string) {
+	listCountCache.invalidate(table)
+
+	responseCaches.RLock()
+	defer responseCaches.RUnlock()
+	for name, caches := range responseCaches.caches {
+		if name != table && !referencesModel(name, table) {
+			continue
+		}
+		for _, cache := range caches {
+			// 失效失败时只能等待缓存过期
+			_, _ = cache.Incr(cacheGenerationKey(name))
+		}
+	}
+}
+
+// referencesModel 模型 name 是否通过关联关系引用了模型 table，展开关联数据时响应中包含 table 的数据
+func referencesModel(name, table string) bool {
+	modelMeta := getModelMeta(name)
+	if modelMeta == nil {
+		return false
+	}
+	for _, a := range modelMeta.Associations {
+		if a.Table == table || a.JoinTable == table {
+			return true
+		}
+	}
+	return false
+}
+
+func cacheGenerationKey(table string) string {
+	return "crud:" + table + ":generation"
+}
+
+// cachedResponse 缓存的响应数据以及条件请求的响应头
+type cachedResponse struct {
+	Data         json.RawMessage `json:"data"`
+	ETag         string          `json:"etag,omitempty"`
+	LastModified time.Time       `json:"last_modified,omitempty"`
+}
+
+// responseCacheKey 生成当前请求的缓存键，由模型、缓存代数、用户、请求路径（包括资源ID）以及排序之后的查询参数组成
+// 没有开启响应缓存或者获取缓存代数失败时返回空
+func (c *Core[T]) responseCacheKey() string {
+	if c.config == nil || c.config.Cache == nil {
+		return ""
+	}
+	table := c.getModel().TableName()
+	raw, ok, err := c.config.Cache.Get(cacheGenerationKey(table))
+	if err != nil {
+		_ = c.ginCtx.Error(err)
+		return ""
+	}
+	var generation int64
+	if ok {
+		generation = cast.ToInt64(string(raw))
+	}
+	user, _ := c.ginCtx.Get("user_id")
+	request := c.ginCtx.Request
+	return fmt.Sprintf("crud:%s:%d:%v:%s?%s", table, generation, user, request.URL.Path, sortedRawQuery(request.URL.RawQuery))
+}
+
+// sortedRawQuery 将原始查询参数按 & 拆分之后排序
+// 不能使用 URL.Query()，其会丢弃包含未编码的 ; 的参数，导致不同的 filter 表达式使用同一个缓存键
+func sortedRawQuery(rawQuery string) string {
+	pairs := strings.Split(rawQuery, "&")
+	pairs = slices.DeleteFunc(pairs, func(pair string) bool { return pair == "" })
+	slices.Sort(pairs)
+	return strings.Join(pairs, "&")
+}
+
+// replayCache 缓存存在时按照条件请求返回 304 或者缓存的响应，返回是否命中
+func (c *Core[T]) replayCache(key string) bool {
+	if key == "" {
+		return false
+	}
+	raw, ok, err := c.config.Cache.Get(key)
+	if err != nil {
+		_ = c.ginCtx.Error(err)
+		return false
+	}
+	var cached cachedResponse
+	if !ok || json.Unmarshal(raw, &cached) != nil {
+		return false
+	}
+	if notModified(c.ginCtx, cached.ETag, cached.LastModified) {
+		return true
+	}
+	HandleRes(c.ginCtx, http.StatusOK, cached.Data, "")
+	return true
+}
+
+// storeCache 缓存响应数据，缓存失败不影响响应
+func (c *Core[T]) storeCache(key string, data interface{}, etag string, modified time.Time) {
+	if key == "" {
+		return
+	}
+	body, err := json.Marshal(data)
+	if err == nil {
+		body, err = json.Marshal(cachedResponse{Data: body, ETag: etag, LastModified: modified})
+	}
+	if err == nil {
+		err = c.config.Cache.Set(key, body, c.config.CacheTTL)
+	}
+	if err != nil {
+		_ = c.ginCtx.Error(err)
+	}
+}
+
+// LRUCache 进程内的 LRU 响应缓存，只适用于单实例部署
+type LRUCache struct {
+	mu         sync.Mutex
+	maxEntries int
+	ll         *list.List
+	items      map[string]*list.Element
+}
+
+type lruEntry struct {
+	key       string
+	value     []byte
+	expiresAt time.Time
+}
+
+// NewLRUCache 创建最多缓存 maxEntries 条记录的 LRU 缓存，超过时淘汰最久没有使用的记录
+func NewLRUCache(maxEntries int) *LRUCache {
+	return &LRUCache{maxEntries: maxEntries, ll: list.New(), items: make(map[string]*list.Element)}
+}
+
+func (l *LRUCache) Get(key string) ([]byte, bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	element, ok := l.items[key]
+	if !ok {
+		return nil, false, nil
+	}
+	entry := element.Value.(*lruEntry)
+	if !entry.expiresAt.IsZero() && time.Now().After(entry.expiresAt) {
+		l.remove(element)
+		return nil, false, nil
+	}
+	l.ll.MoveToFront(element)
+	return entry.value, true, nil
+}
+
+func (l *LRUCache) Set(key string, value []byte, ttl time.Duration) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.set(key, value, ttl)
+	return nil
+}
+
+func (l *LRUCache) Incr(key string) (int64, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var n int64
+	if element, ok := l.items[key]; ok {
+		n = cast.ToInt64(string(element.Value.(*lruEntry).value))
+	}
+	n++
+	l.set(key, []byte(cast.ToString(n)), 0)
+	return n, nil
+}
+
+func (l *LRUCache) set(key string, value []byte, ttl time.Duration) {
+	var expiresAt time.Time
+	if ttl > 0 {
+		expiresAt = time.Now().Add(ttl)
+	}
+	if element, ok := l.items[key]; ok {
+		element.Value = &lruEntry{key: key, value: value, expiresAt: expiresAt}
+		l.ll.MoveToFront(element)
+		return
+	}
+	l.items[key] = l.ll.PushFront(&lruEntry{key: key, value: value, expiresAt: expiresAt})
+	if l.maxEntries > 0 && l.ll.Len() > l.maxEntries {
+		l.remove(l.ll.Back())
+	}
+}
+
+func (l *LRUCache) remove(element *list.Element) {
+	l.ll.Remove(element)
+	delete(l.items, element.Value.(*lruEntry).key)
+}
@@ -0,0 +1,175 @@
+package crud
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+)
+
+// RedisCache 使用 Redis 协议（RESP）的响应缓存，适用于多实例部署，兼容 Redis 协议的服务都可以使用
+// 只使用 GET、SET、INCR 命令，不依赖第三方客户端
+type RedisCache struct {
+	addr     string
+	password string
+	db       int
+	// Timeout 建立连接以及每条命令的超时时间
+	Timeout time.Duration
+	conns   chan *redisConn
+}
+
+type redisConn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// NewRedisCache 创建 Redis 响应缓存，password 为空时不认证，最多保留10个空闲连接
+func NewRedisCache(addr, password string, db int) *RedisCache {
+	return &RedisCache{addr: addr, password: password, db: db, Timeout: 3 * time.Second, conns: make(chan *redisConn, 10)}
+}
+
+func (r *RedisCache) Get(key string) ([]byte, bool, error) {
+	reply, err := r.do("GET", key)
+	if err != nil {
+		return nil, false, err
+	}
+	if reply == nil {
+		return nil, false, nil
+	}
+	value, ok := reply.([]byte)
+	if !ok {
+		return nil, false, fmt.Errorf("redis GET 返回了非字符串类型: %v", reply)
+	}
+	return value, true, nil
+}
+
+func (r *RedisCache) Set(key string, value []byte, ttl time.Duration) error {
+	args := []string{"SET", key, string(value)}
+	if ttl > 0 {
+		args = append(args, "PX", strconv.FormatInt(ttl.Milliseconds(), 10))
+	}
+	_, err := r.do(args...)
+	return err
+}
+
+func (r *RedisCache) Incr(key string) (int64, error) {
+	reply, err := r.do("INCR", key)
+	if err != nil {
+		return 0, err
+	}
+	n, ok := reply.(int64)
+	if !ok {
+		return 0, fmt.Errorf("redis INCR 返回了非整数类型: %v", reply)
+	}
+	return n, nil
+}
+
+// do 执行一条命令，出错的连接直接关闭不再放回连接池
+func (r *RedisCache) do(args ...string) (interface{}, error) {
+	conn, err := r.get()
+	if err != nil {
+		return nil, err
+	}
+	reply, err := conn.do(r.Timeout, args...)
+	var replyErr redisError
+	if err != nil && !errors.As(err, &replyErr) {
+		_ = conn.conn.Close()
+		return nil, err
+	}
+	r.put(conn)
+	return reply, err
+}
+
+func (r *RedisCache) get() (*redisConn, error) {
+	select {
+	case conn := <-r.conns:
+		return conn, nil
+	default:
+	}
+
+	netConn, err := net.DialTimeout("tcp", r.addr, r.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	conn := &redisConn{conn: netConn, reader: bufio.NewReader(netConn)}
+	if r.password != "" {
+		if _, err := conn.do(r.Timeout, "AUTH", r.password); err != nil {
+			_ = netConn.Close()
+			return nil, err
+		}
+	}
+	if r.db != 0 {
+		if _, err := conn.do(r.Timeout, "SELECT", strconv.Itoa(r.db)); err != nil {
+			_ = netConn.Close()
+			return nil, err
+		}
+	}
+	return conn, nil
+}
+
+func (r *RedisCache) put(conn *redisConn) {
+	select {
+	case r.conns <- conn:
+	default:
+		_ = conn.conn.Close()
+	}
+}
+
+// redisError 服务端返回的错误，连接仍然可用
+type redisError string
+
+func (e redisError) Error() string {
+	return "redis: " + string(e)
+}
+
+// do 发送命令并读取回复，回复为字符串时返回 []byte，整数时返回 int64，空值时返回 nil
+func (c *redisConn) do(timeout time.Duration, args ...string) (interface{}, error) {
+	if timeout > 0 {
+		if err := c.conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+			return nil, err
+		}
+	}
+	buf := []byte("*" + strconv.Itoa(len(args)) + "\r\n")
+	for _, arg := range args {
+		buf = append(buf, "$"+strconv.Itoa(len(arg))+"\r\n"...)
+		buf = append(buf, arg...)
+		buf = append(buf, "\r\n"...)
+	}
+	if _, err := c.conn.Write(buf); err != nil {
+		return nil, err
+	}
+	return c.read()
+}
+
+func (c *redisConn) read() (interface{}, error) {
+	line, err := c.reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return nil, fmt.Errorf("redis 回复格式错误: %q", line)
+	}
+	kind, body := line[0], line[1:len(line)-2]
+	switch kind {
+	case '+':
+		return []byte(body), nil
+	case '-':
+		return nil, redisError(body)
+	case ':':
+		return strconv.ParseInt(body, 10, 64)
+	case '$':
+		n, err := strconv.Atoi(body)
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		value := make([]byte, n+2)
+		if _, err := io.ReadFull(c.reader, value); err != nil {
+			return nil, err
+		}
+		return value[:n], nil
+	}
+	return nil, fmt.Errorf("redis 不支持的回复类型: %q", line)
+}
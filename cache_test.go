@@ -0,0 +1,323 @@
+package crud
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/polaris0915/go-crud/model"
+)
+
+func TestResponseCache(t *testing.T) {
+	r, db := newTestServer(t, &model.RelateType{}, &model.File{}, &importUser{})
+	db.Create(&model.RelateType{ID: 1, Type: "a"})
+	db.Create(&model.File{ID: 1, FileName: "f", DisplayName: "f", FilePath: "/f", RelateTypeID: 1})
+	db.Create(&importUser{ID: 1, Email: "a@x.com", Name: "A"})
+
+	cache := NewLRUCache(100)
+	r.Use(func(ctx *gin.Context) { ctx.Set("user_id", ctx.GetHeader("X-User")) })
+	RegisterModelApi[*model.File](r.Group("/api"), "file", ResponseCache(cache, time.Minute))
+	RegisterModelApi[*model.RelateType](r.Group("/api"), "type")
+	RegisterModelApi[*importUser](r.Group("/api"), "user", ResponseCache(cache, time.Minute))
+	queries := countQueries(t, db)
+
+	do := func(method, path, user, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-User", user)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	// get 返回请求是否查询了数据库以及响应
+	get := func(path, user string) (bool, string) {
+		t.Helper()
+		before := atomic.LoadInt64(queries)
+		w := do(http.MethodGet, path, user, "")
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET %s: %d %s", path, w.Code, w.Body.String())
+		}
+		return atomic.LoadInt64(queries) != before, w.Body.String()
+	}
+
+	t.Run("get and list", func(t *testing.T) {
+		for _, path := range []string{"/api/user/1", "/api/user?sort=-name&fields=name"} {
+			_, first := get(path, "u1")
+			if queried, second := get(path, "u1"); queried || second != first {
+				t.Errorf("%s was not cached: %s / %s", path, first, second)
+			}
+		}
+		// 查询参数的顺序不影响缓存
+		if queried, _ := get("/api/user?fields=name&sort=-name", "u1"); queried {
+			t.Error("query was not normalized")
+		}
+		// 不同用户使用不同的缓存
+		if queried, _ := get("/api/user/1", "u2"); !queried {
+			t.Error("cache shared between users")
+		}
+		// 缓存的 ETag 仍然用于条件请求
+		etag := do(http.MethodGet, "/api/user/1", "u1", "").Header().Get("ETag")
+		req := httptest.NewRequest(http.MethodGet, "/api/user/1", nil)
+		req.Header.Set("X-User", "u1")
+		req.Header.Set("If-None-Match", etag)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if etag == "" || w.Code != http.StatusNotModified {
+			t.Errorf("etag = %q, status = %d", etag, w.Code)
+		}
+	})
+
+	t.Run("invalidate", func(t *testing.T) {
+		get("/api/user/1", "u1")
+		get("/api/user", "u1")
+		if w := do(http.MethodPatch, "/api/user/1", "u1", `{"name":"B"}`); w.Code != http.StatusOK {
+			t.Fatalf("update status = %d", w.Code)
+		}
+		if queried, body := get("/api/user/1", "u1"); !queried || !strings.Contains(body, `"name":"B"`) {
+			t.Errorf("stale get: %s", body)
+		}
+		if queried, body := get("/api/user", "u1"); !queried || !strings.Contains(body, `"name":"B"`) {
+			t.Errorf("stale list: %s", body)
+		}
+	})
+
+	t.Run("association", func(t *testing.T) {
+		get("/api/file/1?expand=relate_type", "u1")
+		// 被关联的模型发生变化时，引用了该模型的模型的缓存也失效
+		if w := do(http.MethodPost, "/api/type", "u1", `{"type":"b"}`); w.Code != http.StatusCreated {
+			t.Fatalf("create status = %d", w.Code)
+		}
+		if queried, _ := get("/api/file/1?expand=relate_type", "u1"); !queried {
+			t.Error("stale expand")
+		}
+		// 没有关联关系的模型不受影响
+		get("/api/user/1", "u1")
+		do(http.MethodPost, "/api/type", "u1", `{"type":"c"}`)
+		if queried, _ := get("/api/user/1", "u1"); queried {
+			t.Error("unrelated cache was invalidated")
+		}
+	})
+
+	t.Run("filter expression", func(t *testing.T) {
+		// filter 中未编码的 ; 不能被忽略，不同的表达式使用不同的缓存
+		_, first := get("/api/user?filter=name==B;age==0", "u1")
+		if queried, second := get("/api/user?filter=name==B;age==1", "u1"); !queried || second == first {
+			t.Errorf("filters share cache: %s / %s", first, second)
+		}
+		if queried, _ := get("/api/user?filter=name==B;age==0", "u1"); queried {
+			t.Error("filter was not cached")
+		}
+	})
+}
+
+func TestResponseCacheTransaction(t *testing.T) {
+	// 事务提交之前其他连接仍然可以读到旧数据
+	r, db := newWALTestServer(t, &importUser{})
+	db.Create(&importUser{ID: 1, Email: "a@x.com", Name: "A"})
+	db.Create(&importUser{ID: 2, Email: "b@x.com", Name: "B"})
+
+	get := func(path string) string {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w.Body.String()
+	}
+	// 在事务提交之前查询，读到的旧数据会写入响应缓存
+	readInTx := func(c ICore) error {
+		get("/api/user/1")
+		get("/api/user")
+		return nil
+	}
+	transaction := func(c ICore) error {
+		c.SetTransaction(true)
+		return nil
+	}
+	// 缓存不过期，只能依赖模型变化使之失效
+	RegisterModelApi[*importUser](r.Group("/api"), "user", ResponseCache(NewLRUCache(100), 0),
+		BeforeUpdate(transaction), AfterUpdate(readInTx),
+		BeforeDelete(transaction), AfterDelete(readInTx),
+	)
+
+	req := httptest.NewRequest(http.MethodPatch, "/api/user/1", strings.NewReader(`{"name":"C"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("update: %d %s", w.Code, w.Body.String())
+	}
+	for _, path := range []string{"/api/user/1", "/api/user"} {
+		if body := get(path); !strings.Contains(body, `"name":"C"`) {
+			t.Errorf("stale %s after update: %s", path, body)
+		}
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/api/user/2", nil))
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("delete: %d %s", w.Code, w.Body.String())
+	}
+	if body := get("/api/user"); strings.Contains(body, `"name":"B"`) {
+		t.Errorf("stale list after delete: %s", body)
+	}
+}
+
+func TestLRUCache(t *testing.T) {
+	cache := NewLRUCache(2)
+	_ = cache.Set("a", []byte("1"), 0)
+	_ = cache.Set("b", []byte("2"), 0)
+	cache.Get("a")
+	_ = cache.Set("c", []byte("3"), 0)
+	if _, ok, _ := cache.Get("b"); ok {
+		t.Error("least recently used entry was not evicted")
+	}
+	if value, ok, _ := cache.Get("a"); !ok || string(value) != "1" {
+		t.Errorf("a = %q, %v", value, ok)
+	}
+
+	_ = cache.Set("d", []byte("4"), time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	if _, ok, _ := cache.Get("d"); ok {
+		t.Error("expired entry was returned")
+	}
+	if n, _ := cache.Incr("n"); n != 1 {
+		t.Errorf("incr = %d", n)
+	}
+	if n, _ := cache.Incr("n"); n != 2 {
+		t.Errorf("incr = %d", n)
+	}
+}
+
+func TestRedisCache(t *testing.T) {
+	addr := fakeRedis(t, "secret")
+	cache := NewRedisCache(addr, "secret", 1)
+
+	if _, ok, err := cache.Get("missing"); ok || err != nil {
+		t.Fatalf("missing = %v, %v", ok, err)
+	}
+	if err := cache.Set("key", []byte("a\r\nb"), time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if value, ok, err := cache.Get("key"); !ok || err != nil || string(value) != "a\r\nb" {
+		t.Errorf("key = %q, %v, %v", value, ok, err)
+	}
+	if err := cache.Set("short", []byte("x"), time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(5 * time.Millisecond)
+	if _, ok, _ := cache.Get("short"); ok {
+		t.Error("expired key was returned")
+	}
+	for want := int64(1); want <= 2; want++ {
+		if n, err := cache.Incr("n"); n != want || err != nil {
+			t.Errorf("incr = %d, %v", n, err)
+		}
+	}
+
+	if _, _, err := NewRedisCache(addr, "wrong", 0).Get("key"); err == nil {
+		t.Error("expected auth error")
+	}
+}
+
+// fakeRedis 启动一个只支持 AUTH、SELECT、GET、SET PX、INCR 的 Redis 协议服务
+func fakeRedis(t *testing.T, password string) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+
+	var mu sync.Mutex
+	values := make(map[string]string)
+	expires := make(map[string]time.Time)
+	handle := func(args []string) string {
+		mu.Lock()
+		defer mu.Unlock()
+		switch strings.ToUpper(args[0]) {
+		case "AUTH":
+			if args[1] != password {
+				return "-WRONGPASS invalid password\r\n"
+			}
+			return "+OK\r\n"
+		case "SELECT":
+			return "+OK\r\n"
+		case "GET":
+			value, ok := values[args[1]]
+			if at, expiring := expires[args[1]]; !ok || (expiring && time.Now().After(at)) {
+				return "$-1\r\n"
+			}
+			return "$" + strconv.Itoa(len(value)) + "\r\n" + value + "\r\n"
+		case "SET":
+			values[args[1]] = args[2]
+			delete(expires, args[1])
+			if len(args) == 5 {
+				ms, _ := strconv.Atoi(args[4])
+				expires[args[1]] = time.Now().Add(time.Duration(ms) * time.Millisecond)
+			}
+			return "+OK\r\n"
+		case "INCR":
+			n, _ := strconv.ParseInt(values[args[1]], 10, 64)
+			values[args[1]] = strconv.FormatInt(n+1, 10)
+			return ":" + values[args[1]] + "\r\n"
+		}
+		return "-ERR unknown command\r\n"
+	}
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				reader := bufio.NewReader(conn)
+				for {
+					args, err := readRESPCommand(reader)
+					if err != nil {
+						return
+					}
+					if _, err := io.WriteString(conn, handle(args)); err != nil {
+						return
+					}
+				}
+			}()
+		}
+	}()
+	return listener.Addr().String()
+}
+
+func readRESPCommand(reader *bufio.Reader) ([]string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+	if err != nil {
+		return nil, err
+	}
+	args := make([]string, n)
+	for i := range args {
+		if line, err = reader.ReadString('\n'); err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+		if err != nil {
+			return nil, err
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(reader, buf); err != nil {
+			return nil, err
+		}
+		args[i] = string(buf[:size])
+	}
+	return args, nil
+}
@@ -0,0 +1,68 @@
+package crud
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"time"
+)
+
+// updatedAtField 模型中的 updated_at 字段，用于生成 Last-Modified，没有时返回空
+func (r *RegisteredModel) updatedAtField() *Fields {
+	for _, field := range r.Fields {
+		if field.GormFieldName == "updated_at" {
+			return field
+		}
+	}
+	return nil
+}
+
+// contentETag 根据返回的数据计算强 ETag，map 序列化时键有序，相同的数据得到相同的 ETag
+func contentETag(data interface{}) (string, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(raw)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`, nil
+}
+
+// lastModified 将查询结果中 updated_at 的值转换为时间，不是时间类型时返回零值
+func lastModified(value interface{}) time.Time {
+	switch v := value.(type) {
+	case time.Time:
+		return v
+	case *time.Time:
+		if v != nil {
+			return *v
+		}
+	}
+	return time.Time{}
+}
+
+// notModified 设置 ETag、Last-Modified 响应头，并根据 If-None-Match、If-Modified-Since 判断客户端的缓存是否仍然有效
+// 有效时返回 304 并返回 true，有 If-None-Match 时忽略 If-Modified-Since
+func notModified(ctx *gin.Context, etag string, modified time.Time) bool {
+	if etag != "" {
+		ctx.Header("ETag", etag)
+	}
+	if !modified.IsZero() {
+		ctx.Header("Last-Modified", modified.UTC().Format(http.TimeFormat))
+	}
+
+	fresh := false
+	if header := ctx.GetHeader("If-None-Match"); header != "" {
+		fresh = etag != "" && etagMatch(header, etag, true)
+	} else if header := ctx.GetHeader("If-Modified-Since"); header != "" && !modified.IsZero() {
+		// Last-Modified 只精确到秒
+		since, err := http.ParseTime(header)
+		fresh = err == nil && !modified.Truncate(time.Second).After(since)
+	}
+	if fresh {
+		ctx.Status(http.StatusNotModified)
+		ctx.Writer.WriteHeaderNow()
+	}
+	return fresh
+}
@@ -0,0 +1,70 @@
+package crud
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestConditionalGet(t *testing.T) {
+	r, db := newTestServer(t, &versionNote{}, &importUser{})
+	RegisterModelApi[*versionNote](r.Group("/api"), "note")
+	RegisterModelApi[*importUser](r.Group("/api"), "user")
+
+	get := func(path string, headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		for key, value := range headers {
+			req.Header.Set(key, value)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	updated := time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)
+	db.Create(&versionNote{ID: 1, Body: "a", UpdatedAt: updated})
+	db.Create(&importUser{ID: 1, Email: "a@x.com", Name: "A"})
+
+	t.Run("get", func(t *testing.T) {
+		w := get("/api/note/1", nil)
+		etag := w.Header().Get("ETag")
+		if w.Code != http.StatusOK || etag == "" || w.Header().Get("Last-Modified") != "Wed, 01 May 2024 08:00:00 GMT" {
+			t.Fatalf("status = %d, headers = %v", w.Code, w.Header())
+		}
+		if w := get("/api/note/1", map[string]string{"If-None-Match": etag}); w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+			t.Errorf("If-None-Match status = %d, body = %q", w.Code, w.Body.String())
+		}
+		if w := get("/api/note/1", map[string]string{"If-Modified-Since": "Wed, 01 May 2024 08:00:00 GMT"}); w.Code != http.StatusNotModified {
+			t.Errorf("If-Modified-Since status = %d", w.Code)
+		}
+		if w := get("/api/note/1", map[string]string{"If-Modified-Since": "Wed, 01 May 2024 07:59:59 GMT"}); w.Code != http.StatusOK {
+			t.Errorf("stale If-Modified-Since status = %d", w.Code)
+		}
+
+		// 没有版本字段时使用返回的数据计算 ETag，不同的字段选择对应不同的 ETag
+		all := get("/api/user/1", nil).Header().Get("ETag")
+		name := get("/api/user/1?fields=name", nil).Header().Get("ETag")
+		if all == "" || all == name {
+			t.Errorf("etags = %q, %q", all, name)
+		}
+		if w := get("/api/user/1?fields=name", map[string]string{"If-None-Match": all}); w.Code != http.StatusOK {
+			t.Errorf("mismatched etag status = %d", w.Code)
+		}
+	})
+
+	t.Run("list", func(t *testing.T) {
+		etag := get("/api/user", nil).Header().Get("ETag")
+		if w := get("/api/user", map[string]string{"If-None-Match": etag}); w.Code != http.StatusNotModified {
+			t.Errorf("status = %d", w.Code)
+		}
+		// 分页信息不同时 ETag 不同
+		if w := get("/api/user?per_page=5", map[string]string{"If-None-Match": etag}); w.Code != http.StatusOK {
+			t.Errorf("per_page status = %d", w.Code)
+		}
+		db.Create(&importUser{ID: 2, Email: "b@x.com", Name: "B"})
+		if w := get("/api/user", map[string]string{"If-None-Match": etag}); w.Code != http.StatusOK {
+			t.Errorf("changed list status = %d", w.Code)
+		}
+	})
+}
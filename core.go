@@ -10,6 +10,8 @@ type ICore interface {
 	GetRules() map[string]interface{}
 	SetTransaction(bool)
 	GetModel() CModel
+	// GetAffectedIDs 批量更新、批量删除以及恢复中受影响记录的ID
+	GetAffectedIDs() []uint64
 }
 
 type Core[T CModel] struct {
@@ -32,6 +34,18 @@ type Core[T CModel] struct {
 	payload map[string]interface{}
 	// 校验标签以及规则
 	rules map[string]interface{}
+
+	// 当前模型的配置，通过 Crud 注册的路由会设置该配置，可能为空
+	config *Config
+
+	// 嵌套路由中的父资源，不是嵌套路由时为空
+	parent *nestedParent
+	// 父资源对子资源的限定条件，通过 resolveParent 生成
+	scope *parentScope
+	// 批量更新、批量删除以及恢复中受影响记录的ID
+	affectedIDs []uint64
+	// deleted 软删除记录的查询方式，默认不返回软删除的记录
+	deleted string
 }
 
 // NewCore 实例化最终操作对象
@@ -71,6 +85,10 @@ func (c *Core[T]) GetModel() CModel {
 	return c.model
 }
 
+func (c *Core[T]) GetAffectedIDs() []uint64 {
+	return c.affectedIDs
+}
+
 // HandleRes 全局响应处理函数
 func HandleRes(c *gin.Context, code int, data interface{}, message string) {
 	// TODO 根据data以及message数据的有无，来指定具体的响应形式，减少传输数据的成本
@@ -88,3 +106,17 @@ func HandleRes(c *gin.Context, code int, data interface{}, message string) {
 
 	c.JSON(code, response)
 }
+
+// HandleErr 全局错误响应处理函数
+func HandleErr(c *gin.Context, err *cError.Error) {
+	response := gin.H{
+		"code":    err.Code,
+		"message": err.Message,
+	}
+
+	if detail := err.PublicDetail(); detail != nil {
+		response["detail"] = detail
+	}
+
+	c.JSON(err.HttpStatus, response)
+}
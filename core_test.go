@@ -0,0 +1,31 @@
+package crud
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/polaris0915/go-crud/cError"
+)
+
+func TestHandleErr(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		err    *cError.Error
+		detail bool
+	}{
+		{cError.New(cError.ErrReadFilter, "status 的值无效", errors.New("无效的过滤值")), true},
+		// 服务端错误的详情可能是数据库驱动返回的错误信息，不返回给客户端
+		{cError.New(cError.ErrDBQuery, "no such column: secret", errors.New("no such column: secret")), false},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(w)
+		HandleErr(ctx, tt.err)
+		if w.Code != tt.err.HttpStatus || strings.Contains(w.Body.String(), `"detail"`) != tt.detail {
+			t.Errorf("%d: %d %s", tt.err.Code, w.Code, w.Body.String())
+		}
+	}
+}
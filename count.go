@@ -0,0 +1,153 @@
+package crud
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/polaris0915/go-crud/cError"
+	"gorm.io/gorm"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 列表查询统计总记录数的方式
+const (
+	countExact    = "exact"    // 精确统计，COUNT(*)
+	countEstimate = "estimate" // 使用数据库的表统计信息估算
+	countNone     = "none"     // 不统计总记录数
+)
+
+// countCacheMaxEntries 每个模型最多缓存的统计结果数量，超过后清空该模型的缓存
+const countCacheMaxEntries = 1024
+
+type countCacheEntry struct {
+	total     int64
+	expiresAt time.Time
+}
+
+// countCache 进程内的总记录数缓存
+// 以模型表名以及归一化之后的过滤条件作为键，模型发生创建、更新、删除时失效
+type countCache struct {
+	mu      sync.Mutex
+	entries map[string]map[string]countCacheEntry
+}
+
+var listCountCache = &countCache{entries: make(map[string]map[string]countCacheEntry)}
+
+func (cc *countCache) get(table, key string) (int64, bool) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+
+	entry, ok := cc.entries[table][key]
+	if !ok {
+		return 0, false
+	}
+	if time.Now().After(entry.expiresAt) {
+		delete(cc.entries[table], key)
+		return 0, false
+	}
+	return entry.total, true
+}
+
+func (cc *countCache) set(table, key string, total int64, ttl time.Duration) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+
+	entries := cc.entries[table]
+	if entries == nil || len(entries) >= countCacheMaxEntries {
+		entries = make(map[string]countCacheEntry)
+		cc.entries[table] = entries
+	}
+	entries[key] = countCacheEntry{total: total, expiresAt: time.Now().Add(ttl)}
+}
+
+// invalidate 清空模型的所有统计缓存
+func (cc *countCache) invalidate(table string) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+
+	delete(cc.entries, table)
+}
+
+// filterKey 归一化之后的过滤条件，由编译之后的查询语句和参数组成
+// 参数使用 JSON 编码，%v 会将 [a b] 与 ["a b"] 格式化成相同的字符串
+type filterKey []string
+
+func (k *filterKey) add(query string, args []interface{}) {
+	encoded, err := json.Marshal(args)
+	if err != nil {
+		encoded = []byte(fmt.Sprintf("%#v", args))
+	}
+	*k = append(*k, query+" "+string(encoded))
+}
+
+func (k filterKey) String() string {
+	return strings.Join(k, " AND ")
+}
+
+// countTotal 按照 mode 统计 db 中符合条件的记录数
+// 返回的 total 为空表示没有统计，estimated 表示结果是估算值
+func (c *Core[T]) countTotal(db *gorm.DB, mode string, key filterKey) (total *int64, estimated bool) {
+	table := c.getModel().TableName()
+
+	switch mode {
+	case countNone:
+		return nil, false
+
+	case countEstimate:
+		// 表统计信息只能估算整张表的记录数（包括软删除的记录），有过滤条件时仍然精确统计
+		if len(key) == 0 {
+			if n, ok := estimateCount(db, table); ok {
+				return &n, true
+			}
+		}
+	}
+
+	var ttl time.Duration
+	if c.config != nil {
+		ttl = c.config.CountCacheTTL
+	}
+	if ttl > 0 {
+		if n, ok := listCountCache.get(table, key.String()); ok {
+			return &n, false
+		}
+	}
+
+	var n int64
+	countDB := db
+	if err := countDB.Count(&n).Error; err != nil {
+		c.err = cError.New(cError.ErrDBQuery, nil, err)
+		return nil, false
+	}
+	if ttl > 0 {
+		listCountCache.set(table, key.String(), n, ttl)
+	}
+	return &n, false
+}
+
+// estimateCount 使用数据库的表统计信息估算表的记录数，不支持的数据库返回 false
+func estimateCount(db *gorm.DB, table string) (int64, bool) {
+	var n int64
+	var err error
+	conn := db.Session(&gorm.Session{NewDB: true})
+
+	switch db.Dialector.Name() {
+	case "mysql":
+		err = conn.Raw(
+			"SELECT TABLE_ROWS FROM information_schema.TABLES WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ?", table,
+		).Scan(&n).Error
+	case "postgres":
+		// 表从未被 ANALYZE 过时 reltuples 为 -1
+		err = conn.Raw("SELECT reltuples::bigint FROM pg_class WHERE oid = to_regclass(?)", table).Scan(&n).Error
+		if n < 0 {
+			return 0, false
+		}
+	default:
+		return 0, false
+	}
+
+	if err != nil {
+		return 0, false
+	}
+	return n, true
+}
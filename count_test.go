@@ -0,0 +1,217 @@
+package crud
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"github.com/polaris0915/go-crud/cError"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestCountMode(t *testing.T) {
+	tests := []struct {
+		count  string
+		cursor bool
+		want   string
+		ok     bool
+	}{
+		{"", false, countExact, true},
+		{"", true, countNone, true},
+		{"exact", true, countExact, true},
+		{"estimate", false, countEstimate, true},
+		{"none", false, countNone, true},
+		{"EXACT", false, "", false},
+		{"all", false, "", false},
+	}
+	for _, tt := range tests {
+		q := &listQuery{Count: tt.count, CursorMode: tt.cursor}
+		got, err := q.countMode()
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("count=%q cursor=%v: got %q, err = %v", tt.count, tt.cursor, got, err)
+		}
+	}
+}
+
+func TestCountCache(t *testing.T) {
+	cc := &countCache{entries: make(map[string]map[string]countCacheEntry)}
+	cc.set("a", "status = ? [open]", 3, time.Minute)
+	cc.set("a", "", 10, time.Minute)
+	cc.set("b", "", 7, time.Minute)
+	cc.set("a", "expired", 1, -time.Second)
+
+	tests := []struct {
+		table, key string
+		want       int64
+		ok         bool
+	}{
+		{"a", "status = ? [open]", 3, true},
+		{"a", "", 10, true},
+		{"b", "", 7, true},
+		{"a", "status = ? [closed]", 0, false},
+		{"a", "expired", 0, false},
+	}
+	for _, tt := range tests {
+		if got, ok := cc.get(tt.table, tt.key); got != tt.want || ok != tt.ok {
+			t.Errorf("%s %q: got %d %v, want %d %v", tt.table, tt.key, got, ok, tt.want, tt.ok)
+		}
+	}
+
+	// 失效只影响对应的模型
+	cc.invalidate("a")
+	if _, ok := cc.get("a", ""); ok {
+		t.Error("a: cache not invalidated")
+	}
+	if _, ok := cc.get("b", ""); !ok {
+		t.Error("b: cache invalidated")
+	}
+}
+
+func TestFilterKey(t *testing.T) {
+	meta := newFilterTestMeta()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{DryRun: true, Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := func(rawQuery string) string {
+		w := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(w)
+		ctx.Request = httptest.NewRequest(http.MethodGet, "/?"+rawQuery, nil)
+		c := &Core[*filterTestModel]{ginCtx: ctx}
+		conditions, where := c.parseFilterParams(meta, listQueryParams)
+		if c.err != nil {
+			t.Fatalf("%s: %v", rawQuery, c.err)
+		}
+		_, key := c.applyFilters(db.Model(&filterTestModel{}), meta, conditions, where)
+		return key.String()
+	}
+
+	// 参数的顺序以及分页、排序参数不影响统计缓存的键
+	base := key("status=open&id=gte:10")
+	if base != `id >= ? [10] AND status = ? ["open"]` {
+		t.Errorf("key = %q", base)
+	}
+	for _, rawQuery := range []string{"id=gte:10&status=open", "page=2&status=open&sort=-id&id=gte:10"} {
+		if got := key(rawQuery); got != base {
+			t.Errorf("%s: key = %q, want %q", rawQuery, got, base)
+		}
+	}
+	if got := key("status=open&id=gte:11"); got == base {
+		t.Errorf("different filters share key %q", got)
+	}
+	// 集合中的值包含空格时不能与多个值的集合混淆
+	if key("status=in:a,b") == key("status=in:a%20b") {
+		t.Error("in:a,b and in:\"a b\" share key")
+	}
+	if got := key(""); got != "" {
+		t.Errorf("no filter: key = %q", got)
+	}
+}
+
+type countTask struct {
+	ID     uint64 `gorm:"column:id;primary_key" json:"id" crud:"allow_get"`
+	Status string `gorm:"column:status" json:"status" crud:"allow_get"`
+}
+
+func (s *countTask) TableName() string { return "count_task" }
+
+func TestListCount(t *testing.T) {
+	// 删除事务提交之前其他连接仍然可以读到旧数据
+	r, db := newWALTestServer(t, &countTask{})
+	queries := countQueries(t, db)
+
+	var readInTx bool
+	RegisterModelApi[*countTask](r.Group("/api"), "task", CountCache(time.Minute),
+		BeforeDelete(func(c ICore) error {
+			c.SetTransaction(true)
+			return nil
+		}),
+		AfterDelete(func(c ICore) error {
+			// 在删除事务提交之前查询列表，读到的旧总数会写入统计缓存
+			if readInTx {
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/task", nil))
+			}
+			return nil
+		}),
+	)
+	for i, status := range []string{"open", "open", "closed"} {
+		db.Create(&countTask{ID: uint64(i + 1), Status: status})
+	}
+
+	type listResponse struct {
+		Code int `json:"code"`
+		Data struct {
+			Pagination map[string]interface{} `json:"pagination"`
+		} `json:"data"`
+	}
+	list := func(t *testing.T, query string) (map[string]interface{}, int64) {
+		t.Helper()
+		before := *queries
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/task"+query, nil))
+		var resp listResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || resp.Code != http.StatusOK {
+			t.Fatalf("%s: %s", query, w.Body.String())
+		}
+		return resp.Data.Pagination, *queries - before
+	}
+
+	t.Run("modes", func(t *testing.T) {
+		tests := []struct {
+			query string
+			want  string
+		}{
+			{"", "map[current_page:1 per_page:10 total:3 total_pages:1]"},
+			{"?count=exact&status=open", "map[current_page:1 per_page:10 total:2 total_pages:1]"},
+			// sqlite 不支持估算，退回精确统计
+			{"?count=estimate", "map[current_page:1 per_page:10 total:3 total_pages:1]"},
+			{"?count=none", "map[current_page:1 per_page:10]"},
+			{"?limit=2", "map[limit:2 next_cursor:]"},
+			{"?limit=2&count=exact", "map[limit:2 next_cursor: total:3]"},
+		}
+		for _, tt := range tests {
+			pagination, _ := list(t, tt.query)
+			if next, ok := pagination["next_cursor"].(string); ok && next != "" {
+				pagination["next_cursor"] = ""
+			}
+			if got := fmt.Sprint(pagination); got != tt.want {
+				t.Errorf("%s: pagination = %s, want %s", tt.query, got, tt.want)
+			}
+		}
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/task?count=all", nil))
+		if !strings.Contains(w.Body.String(), fmt.Sprintf(`"code":%d`, cError.ErrReadPagination)) {
+			t.Errorf("count=all: %s", w.Body.String())
+		}
+	})
+
+	t.Run("cache", func(t *testing.T) {
+		// 第二次查询命中统计缓存，只查询数据
+		list(t, "?status=open")
+		if _, n := list(t, "?status=open&page=2"); n != 1 {
+			t.Errorf("cached count: %d queries, want 1", n)
+		}
+
+		readInTx = true
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/api/task/1", nil))
+		readInTx = false
+		if w.Code != http.StatusNoContent {
+			t.Fatalf("delete: %d %s", w.Code, w.Body.String())
+		}
+		for query, want := range map[string]float64{"": 2, "?status=open": 1} {
+			if pagination, _ := list(t, query); pagination["total"] != want {
+				t.Errorf("%s after delete: total = %v, want %v", query, pagination["total"], want)
+			}
+		}
+	})
+}
@@ -2,15 +2,25 @@ package crud
 
 import (
 	"errors"
+	"fmt"
 	"github.com/polaris0915/go-crud/cError"
 	"github.com/polaris0915/go-crud/model"
+	"github.com/spf13/cast"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"net/http"
+	"strings"
 )
 
 func (c *Core[T]) Create() {
 	//// 1. 获取新的模型T的对象
 	//jsonModel := c.getModel()
 
+	// 2. 嵌套路由检查父资源是否存在
+	if c.resolveParent(); c.err != nil {
+		return
+	}
+
 	// 3. 绑定请求数据
 	if err := c.ginCtx.ShouldBindJSON(&c.payload); err != nil {
 		// TODO detail字段可以更加详细
@@ -18,14 +28,117 @@ func (c *Core[T]) Create() {
 		c.err = cError.New(cError.ErrCreateMissingField, nil, errors.New("请求参数解析错误"))
 		return
 	}
+	// 嵌套路由中子资源的外键由父资源决定
+	c.injectScope(c.payload)
+
+	// 解析返回的字段以及关联数据展开参数，与 Get 相同
+	modelMeta := getModelMeta(c.getModel().TableName())
+	requestedFields, expandRelations := c.readParams()
+	if c.err != nil {
+		return
+	}
+	if c.checkReadParams(modelMeta, requestedFields, expandRelations); c.err != nil {
+		return
+	}
 
+	// 4. 校验请求数据，检查唯一性约束并转换为模型
+	// on_conflict=skip|update 时不检查唯一性约束，由数据库处理冲突
+	onConflict := c.ginCtx.DefaultQuery("on_conflict", conflictFail)
+	if onConflict != conflictFail && onConflict != conflictSkip && onConflict != conflictUpdate {
+		err := fmt.Errorf("on_conflict 只能是 %s、%s 或 %s", conflictFail, conflictSkip, conflictUpdate)
+		c.err = cError.New(cError.ErrCreateValidation, err.Error(), err)
+		return
+	}
+	if c.validateCreate(); c.err != nil {
+		return
+	}
+	var conflict *clause.OnConflict
+	var existing bool
+	if onConflict == conflictFail {
+		c.checkCreateUniqueness()
+	} else {
+		conflict, existing = c.upsertClause(onConflict)
+	}
+	if c.err != nil {
+		return
+	}
+	if c.decodeCreate(); c.err != nil {
+		return
+	}
+
+	// 开启事务（如果启用）
+	var tx = model.Use()
+	if c.enableTransaction {
+		tx = model.Use().Begin()
+		if tx.Error != nil {
+			c.err = cError.New(cError.ErrDBTransaction, nil, tx.Error)
+			return
+		}
+	}
+
+	// 执行创建操作
+	db := tx
+	if conflict != nil {
+		db = tx.Clauses(conflict)
+	}
+	c.insert(db)
+	if c.enableTransaction {
+		if c.err != nil {
+			tx.Rollback()
+			return
+		}
+		if err := tx.Commit().Error; err != nil {
+			c.err = cError.New(cError.ErrDBTransaction, nil, err)
+			return
+		}
+	}
+	if c.err != nil {
+		return
+	}
+	invalidateModel(c.model.TableName())
+
+	// 5. 查询创建的记录，返回的字段与 Get 相同，并设置 Location 响应头
+	// upsert 时数据库不一定返回已有记录的ID，使用冲突字段查询；更新或者跳过已有记录时返回 200，不设置 Location
+	status := http.StatusCreated
+	if existing {
+		status = http.StatusOK
+	}
+	id := cast.ToUint64(modelID(c.model))
+	if conflict != nil {
+		id = c.conflictID(conflict)
+	}
+	if c.err != nil {
+		return
+	}
+	if id == 0 {
+		HandleRes(c.ginCtx, status, true, "")
+		return
+	}
+	result, extraFields := c.readOne(modelMeta, id, requestedFields, expandRelations)
+	if c.err != nil {
+		return
+	}
+	for _, key := range extraFields {
+		delete(result, key)
+	}
+	if !existing {
+		c.ginCtx.Header("Location", fmt.Sprintf("%s/%d", strings.TrimSuffix(c.ginCtx.Request.URL.Path, "/"), id))
+	}
+
+	// 返回成功响应
+	HandleRes(c.ginCtx, status, result, "")
+}
+
+// validateCreate 根据创建规则校验请求数据
+func (c *Core[T]) validateCreate() {
 	// TODO 根据tag检查字段
 	if errs := UseValidator().ValidateMap(c.payload, c.rules); len(errs) > 0 {
 		c.err = cError.New(cError.ErrCreateMissingField, nil, errors.New("请求参数解析错误"))
-		return
 	}
+}
 
-	// 4. 检查唯一性约束
+// checkCreateUniqueness 检查请求数据中的唯一字段是否已经存在
+func (c *Core[T]) checkCreateUniqueness() {
 	if err := checkUniqueness(func() CModel { return c.getModel() }, c.payload); err != nil {
 		// 数据重复
 		if errors.Is(err, errDataDuplicated) {
@@ -34,9 +147,11 @@ func (c *Core[T]) Create() {
 		}
 		// 不是数据重复的错误
 		c.err = cError.New(cError.ErrCreateGeneral, nil, err)
-		return
 	}
+}
 
+// decodeCreate 将请求数据转换为模型，并执行创建前置钩子
+func (c *Core[T]) decodeCreate() {
 	err := weakDecode(c.payload, &c.model)
 	if err != nil {
 		c.err = cError.New(cError.ErrCreateGeneral, nil, err)
@@ -50,39 +165,33 @@ func (c *Core[T]) Create() {
 			return
 		}
 	}
+}
 
-	// 开启事务（如果启用）
-	var tx = model.Use()
-	if c.enableTransaction {
-		tx = model.Use().Begin()
-		if tx.Error != nil {
-			c.err = cError.New(cError.ErrDBTransaction, nil, tx.Error)
-			return
-		}
-		defer func() {
-			if c.err != nil {
-				tx.Rollback()
-			} else {
-				tx.Commit()
-			}
-		}()
-	}
-
-	// 执行创建操作
-	result := tx.Create(c.model)
-	if result.Error != nil {
-		c.err = cError.New(cError.ErrCreateGeneral, nil, result.Error)
+// insert 使用 tx 创建模型，并执行创建后置钩子
+func (c *Core[T]) insert(tx *gorm.DB) {
+	if err := tx.Create(c.model).Error; err != nil {
+		c.err = cError.New(cError.ErrCreateGeneral, nil, err)
 		return
 	}
+	c.afterCreate()
+}
 
-	// 后置钩子
+// afterCreate 执行创建后置钩子
+func (c *Core[T]) afterCreate() {
 	if c.afterHook != nil {
 		if err := c.afterHook(c); err != nil {
 			c.err = cError.New(cError.ErrCreateHookFailure, nil, errors.New("更新后置钩子函数执行失败"))
 			return
 		}
 	}
+}
 
-	// 返回成功响应
-	HandleRes(c.ginCtx, http.StatusCreated, true, "")
+// itemCore 批量操作中每条记录使用单独的核心对象，钩子函数中获取到的是当前记录的数据
+func (c *Core[T]) itemCore(payload map[string]interface{}) *Core[T] {
+	item := NewCore[T](c.ginCtx, c.getModel, c.beforeHook, c.afterHook, c.rules)
+	item.config, item.parent, item.scope = c.config, c.parent, c.scope
+	item.payload = payload
+	// 嵌套路由中子资源的外键由父资源决定
+	item.injectScope(item.payload)
+	return item
 }
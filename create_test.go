@@ -0,0 +1,57 @@
+package crud
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCreateResponse(t *testing.T) {
+	r, db := newTestServer(t, &importUser{})
+	RegisterModelApi[*importUser](r.Group("/api"), "user")
+
+	create := func(query, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/user"+query, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	data := func(w *httptest.ResponseRecorder) map[string]interface{} {
+		var resp struct {
+			Data map[string]interface{} `json:"data"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		return resp.Data
+	}
+
+	// 默认返回所有 allow_get 的字段，id 没有 allow_get 标签不返回
+	w := create("", `{"email":"a@x.com","name":"A","age":20}`)
+	if w.Code != http.StatusCreated || w.Header().Get("Location") != "/api/user/1" {
+		t.Fatalf("status = %d, location = %q", w.Code, w.Header().Get("Location"))
+	}
+	want := map[string]interface{}{"email": "a@x.com", "name": "A", "age": float64(20)}
+	if got := data(w); !reflect.DeepEqual(got, want) {
+		t.Errorf("data = %v, want %v", got, want)
+	}
+
+	w = create("?fields=name", `{"email":"b@x.com","name":"B"}`)
+	if got := data(w); w.Header().Get("Location") != "/api/user/2" || !reflect.DeepEqual(got, map[string]interface{}{"name": "B"}) {
+		t.Errorf("location = %q, data = %v", w.Header().Get("Location"), got)
+	}
+
+	// 返回字段不合法时不创建记录
+	if w := create("?fields=id", `{"email":"c@x.com","name":"C"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("invalid fields status = %d", w.Code)
+	}
+	var n int64
+	db.Model(&importUser{}).Count(&n)
+	if n != 2 {
+		t.Errorf("count = %d, want 2", n)
+	}
+}
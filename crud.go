@@ -11,10 +11,20 @@ type CModel interface {
 
 type ICrud interface {
 	Create() []gin.HandlerFunc
+	CreateBatch() []gin.HandlerFunc
 	Delete() []gin.HandlerFunc
 	Update() []gin.HandlerFunc
+	Replace() []gin.HandlerFunc
+	BulkUpdate() []gin.HandlerFunc
+	BulkDelete() []gin.HandlerFunc
 	Get() []gin.HandlerFunc
 	GetList() []gin.HandlerFunc
+	Search() []gin.HandlerFunc
+	Aggregate() []gin.HandlerFunc
+	Export() []gin.HandlerFunc
+	Import() []gin.HandlerFunc
+	Restore() []gin.HandlerFunc
+	BulkRestore() []gin.HandlerFunc
 }
 
 // Crud
@@ -25,6 +35,8 @@ type Crud[T CModel] struct {
 	// config 保存当前模型所有的执行钩子
 	// 例如 创建前 创建后等等
 	config Config
+	// parent 嵌套路由中的父资源，不是嵌套路由时为空
+	parent *nestedParent
 }
 
 func newCrud[T CModel](getModel func() T, opts ...Option) *Crud[T] {
@@ -35,6 +47,9 @@ func newCrud[T CModel](getModel func() T, opts ...Option) *Crud[T] {
 	for _, opt := range opts {
 		opt(&config)
 	}
+	if config.Cache != nil {
+		registerResponseCache(getModel().TableName(), config.Cache)
+	}
 
 	return &Crud[T]{
 		config:   config,
@@ -47,6 +62,8 @@ func (c *Crud[T]) Create() (ginHandlers []gin.HandlerFunc) {
 	//var ginHandlers []gin.HandlerFunc
 	// 添加路由中间件
 	ginHandlers = append(ginHandlers, c.config.CreateMiddlewares...)
+	// 处理 Idempotency-Key 请求头
+	ginHandlers = append(ginHandlers, c.config.idempotent()...)
 	// 添加实际路由执行函数
 	ginHandlers = append(
 		ginHandlers,
@@ -57,14 +74,40 @@ func (c *Crud[T]) Create() (ginHandlers []gin.HandlerFunc) {
 				c.config.BeforeCreate, c.config.AfterCreate, // 创建前置钩子，猴子钩子
 				getModelMeta(c.GetModel().TableName()).Rules["create"], // 校验规则
 			)
+			core.config, core.parent = &c.config, c.parent
 			// 执行创建函数
 			core.Create()
 			// 如果有错误，组织错误响应
 			if core.err != nil {
-				ginCtx.JSON(core.err.HttpStatus, gin.H{
-					"code":    core.err.Code,
-					"message": core.err.Message,
-				})
+				HandleErr(ginCtx, core.err)
+				return
+			}
+		})
+	return ginHandlers
+}
+
+// CreateBatch 实例化批量创建函数，与 Create 共用中间件、钩子以及校验规则
+func (c *Crud[T]) CreateBatch() (ginHandlers []gin.HandlerFunc) {
+	// 添加路由中间件
+	ginHandlers = append(ginHandlers, c.config.CreateMiddlewares...)
+	// 处理 Idempotency-Key 请求头
+	ginHandlers = append(ginHandlers, c.config.idempotent()...)
+	// 添加实际路由执行函数
+	ginHandlers = append(
+		ginHandlers,
+		func(ginCtx *gin.Context) {
+			// 实例化核心对象
+			core := NewCore[T](
+				ginCtx, c.GetModel,
+				c.config.BeforeCreate, c.config.AfterCreate,
+				getModelMeta(c.GetModel().TableName()).Rules["create"],
+			)
+			core.config, core.parent = &c.config, c.parent
+			// 执行批量创建函数
+			core.CreateBatch()
+			// 如果有错误，组织错误响应
+			if core.err != nil {
+				HandleErr(ginCtx, core.err)
 				return
 			}
 		})
@@ -86,14 +129,12 @@ func (c *Crud[T]) Delete() (ginHandlers []gin.HandlerFunc) {
 				c.config.BeforeDelete, c.config.AfterDelete,
 				getModelMeta(c.GetModel().TableName()).Rules["delete"],
 			)
+			core.config, core.parent = &c.config, c.parent
 			// 执行创建函数
 			core.Delete()
 			// 如果有错误，组织错误响应
 			if core.err != nil {
-				ginCtx.JSON(core.err.HttpStatus, gin.H{
-					"code":    core.err.Code,
-					"message": core.err.Message,
-				})
+				HandleErr(ginCtx, core.err)
 				return
 			}
 		})
@@ -105,6 +146,8 @@ func (c *Crud[T]) Update() (ginHandlers []gin.HandlerFunc) {
 	//var ginHandlers []gin.HandlerFunc
 	// 添加路由中间件
 	ginHandlers = append(ginHandlers, c.config.UpdateMiddlewares...)
+	// 处理 Idempotency-Key 请求头
+	ginHandlers = append(ginHandlers, c.config.idempotent()...)
 	// 添加实际路由执行函数
 	ginHandlers = append(
 		ginHandlers,
@@ -115,14 +158,94 @@ func (c *Crud[T]) Update() (ginHandlers []gin.HandlerFunc) {
 				c.config.BeforeUpdate, c.config.AfterUpdate,
 				getModelMeta(c.GetModel().TableName()).Rules["update"],
 			)
+			core.config, core.parent = &c.config, c.parent
 			// 执行创建函数
 			core.Update()
 			// 如果有错误，组织错误响应
 			if core.err != nil {
-				ginCtx.JSON(core.err.HttpStatus, gin.H{
-					"code":    core.err.Code,
-					"message": core.err.Message,
-				})
+				HandleErr(ginCtx, core.err)
+				return
+			}
+		})
+	return ginHandlers
+}
+
+// Replace 实例化整体替换函数，与 Update 共用中间件、钩子以及校验规则
+func (c *Crud[T]) Replace() (ginHandlers []gin.HandlerFunc) {
+	// 添加路由中间件
+	ginHandlers = append(ginHandlers, c.config.UpdateMiddlewares...)
+	// 处理 Idempotency-Key 请求头
+	ginHandlers = append(ginHandlers, c.config.idempotent()...)
+	// 添加实际路由执行函数
+	ginHandlers = append(
+		ginHandlers,
+		func(ginCtx *gin.Context) {
+			// 实例化核心对象
+			core := NewCore[T](
+				ginCtx, c.GetModel,
+				c.config.BeforeUpdate, c.config.AfterUpdate,
+				getModelMeta(c.GetModel().TableName()).Rules["update"],
+			)
+			core.config, core.parent = &c.config, c.parent
+			// 执行整体替换函数
+			core.Replace()
+			// 如果有错误，组织错误响应
+			if core.err != nil {
+				HandleErr(ginCtx, core.err)
+				return
+			}
+		})
+	return ginHandlers
+}
+
+// BulkUpdate 实例化批量更新函数，与 Update 共用中间件以及钩子
+func (c *Crud[T]) BulkUpdate() (ginHandlers []gin.HandlerFunc) {
+	// 添加路由中间件
+	ginHandlers = append(ginHandlers, c.config.UpdateMiddlewares...)
+	// 处理 Idempotency-Key 请求头
+	ginHandlers = append(ginHandlers, c.config.idempotent()...)
+	// 添加实际路由执行函数
+	ginHandlers = append(
+		ginHandlers,
+		func(ginCtx *gin.Context) {
+			// 实例化核心对象
+			core := NewCore[T](
+				ginCtx, c.GetModel,
+				c.config.BeforeUpdate, c.config.AfterUpdate,
+				getModelMeta(c.GetModel().TableName()).Rules["update"],
+			)
+			core.config, core.parent = &c.config, c.parent
+			// 执行批量更新函数
+			core.BulkUpdate()
+			// 如果有错误，组织错误响应
+			if core.err != nil {
+				HandleErr(ginCtx, core.err)
+				return
+			}
+		})
+	return ginHandlers
+}
+
+// BulkDelete 实例化批量删除函数，与 Delete 共用中间件以及钩子
+func (c *Crud[T]) BulkDelete() (ginHandlers []gin.HandlerFunc) {
+	// 添加路由中间件
+	ginHandlers = append(ginHandlers, c.config.DeleteMiddlewares...)
+	// 添加实际路由执行函数
+	ginHandlers = append(
+		ginHandlers,
+		func(ginCtx *gin.Context) {
+			// 实例化核心对象
+			core := NewCore[T](
+				ginCtx, c.GetModel,
+				c.config.BeforeDelete, c.config.AfterDelete,
+				getModelMeta(c.GetModel().TableName()).Rules["delete"],
+			)
+			core.config, core.parent = &c.config, c.parent
+			// 执行批量删除函数
+			core.BulkDelete()
+			// 如果有错误，组织错误响应
+			if core.err != nil {
+				HandleErr(ginCtx, core.err)
 				return
 			}
 		})
@@ -143,14 +266,12 @@ func (c *Crud[T]) Get() (ginHandlers []gin.HandlerFunc) {
 				c.config.BeforeGet, c.config.AfterGet,
 				getModelMeta(c.GetModel().TableName()).Rules["get"],
 			)
+			core.config, core.parent = &c.config, c.parent
 			// 执行创建函数
 			core.Get()
 			// 如果有错误，组织错误响应
 			if core.err != nil {
-				ginCtx.JSON(core.err.HttpStatus, gin.H{
-					"code":    core.err.Code,
-					"message": core.err.Message,
-				})
+				HandleErr(ginCtx, core.err)
 				return
 			}
 		})
@@ -171,14 +292,170 @@ func (c *Crud[T]) GetList() (ginHandlers []gin.HandlerFunc) {
 				c.config.BeforeGetList, c.config.AfterGetList,
 				getModelMeta(c.GetModel().TableName()).Rules["get"],
 			)
+			core.config, core.parent = &c.config, c.parent
 			// 执行创建函数
 			core.GetList()
 			// 如果有错误，组织错误响应
 			if core.err != nil {
-				ginCtx.JSON(core.err.HttpStatus, gin.H{
-					"code":    core.err.Code,
-					"message": core.err.Message,
-				})
+				HandleErr(ginCtx, core.err)
+				return
+			}
+		})
+	return ginHandlers
+}
+
+// Search 实例化请求体查询函数，与 GetList 共用中间件以及钩子
+func (c *Crud[T]) Search() (ginHandlers []gin.HandlerFunc) {
+	// 添加路由中间件
+	ginHandlers = append(ginHandlers, c.config.GetListMiddlewares...)
+	// 添加实际路由执行函数
+	ginHandlers = append(
+		ginHandlers,
+		func(ginCtx *gin.Context) {
+			// 实例化核心对象
+			core := NewCore[T](
+				ginCtx, c.GetModel,
+				c.config.BeforeGetList, c.config.AfterGetList,
+				getModelMeta(c.GetModel().TableName()).Rules["get"],
+			)
+			core.config, core.parent = &c.config, c.parent
+			// 执行查询函数
+			core.Search()
+			// 如果有错误，组织错误响应
+			if core.err != nil {
+				HandleErr(ginCtx, core.err)
+				return
+			}
+		})
+	return ginHandlers
+}
+
+// Aggregate 实例化聚合查询函数，与 GetList 共用中间件以及钩子
+func (c *Crud[T]) Aggregate() (ginHandlers []gin.HandlerFunc) {
+	// 添加路由中间件
+	ginHandlers = append(ginHandlers, c.config.GetListMiddlewares...)
+	// 添加实际路由执行函数
+	ginHandlers = append(
+		ginHandlers,
+		func(ginCtx *gin.Context) {
+			// 实例化核心对象
+			core := NewCore[T](
+				ginCtx, c.GetModel,
+				c.config.BeforeGetList, c.config.AfterGetList,
+				getModelMeta(c.GetModel().TableName()).Rules["get"],
+			)
+			core.config, core.parent = &c.config, c.parent
+			// 执行聚合查询函数
+			core.Aggregate()
+			// 如果有错误，组织错误响应
+			if core.err != nil {
+				HandleErr(ginCtx, core.err)
+				return
+			}
+		})
+	return ginHandlers
+}
+
+// Export 实例化导出函数，与 GetList 共用中间件以及钩子
+func (c *Crud[T]) Export() (ginHandlers []gin.HandlerFunc) {
+	// 添加路由中间件
+	ginHandlers = append(ginHandlers, c.config.GetListMiddlewares...)
+	// 添加实际路由执行函数
+	ginHandlers = append(
+		ginHandlers,
+		func(ginCtx *gin.Context) {
+			// 实例化核心对象
+			core := NewCore[T](
+				ginCtx, c.GetModel,
+				c.config.BeforeGetList, c.config.AfterGetList,
+				getModelMeta(c.GetModel().TableName()).Rules["get"],
+			)
+			core.config, core.parent = &c.config, c.parent
+			// 执行导出函数，开始写入数据之后的错误无法再组织错误响应
+			core.Export()
+			// 如果有错误，组织错误响应
+			if core.err != nil {
+				HandleErr(ginCtx, core.err)
+				return
+			}
+		})
+	return ginHandlers
+}
+
+// Import 实例化批量导入函数，与 Create 共用中间件、钩子以及校验规则
+func (c *Crud[T]) Import() (ginHandlers []gin.HandlerFunc) {
+	// 添加路由中间件
+	ginHandlers = append(ginHandlers, c.config.CreateMiddlewares...)
+	// 处理 Idempotency-Key 请求头
+	ginHandlers = append(ginHandlers, c.config.idempotent()...)
+	// 添加实际路由执行函数
+	ginHandlers = append(
+		ginHandlers,
+		func(ginCtx *gin.Context) {
+			// 实例化核心对象
+			core := NewCore[T](
+				ginCtx, c.GetModel,
+				c.config.BeforeCreate, c.config.AfterCreate,
+				getModelMeta(c.GetModel().TableName()).Rules["create"],
+			)
+			core.config, core.parent = &c.config, c.parent
+			// 执行导入函数
+			core.Import()
+			// 如果有错误，组织错误响应
+			if core.err != nil {
+				HandleErr(ginCtx, core.err)
+				return
+			}
+		})
+	return ginHandlers
+}
+
+// Restore 实例化恢复软删除记录函数
+func (c *Crud[T]) Restore() (ginHandlers []gin.HandlerFunc) {
+	// 添加路由中间件
+	ginHandlers = append(ginHandlers, c.config.RestoreMiddlewares...)
+	// 添加实际路由执行函数
+	ginHandlers = append(
+		ginHandlers,
+		func(ginCtx *gin.Context) {
+			// 实例化核心对象
+			core := NewCore[T](
+				ginCtx, c.GetModel,
+				c.config.BeforeRestore, c.config.AfterRestore,
+				getModelMeta(c.GetModel().TableName()).Rules["update"],
+			)
+			core.config, core.parent = &c.config, c.parent
+			// 执行恢复函数
+			core.Restore()
+			// 如果有错误，组织错误响应
+			if core.err != nil {
+				HandleErr(ginCtx, core.err)
+				return
+			}
+		})
+	return ginHandlers
+}
+
+// BulkRestore 实例化批量恢复软删除记录函数
+func (c *Crud[T]) BulkRestore() (ginHandlers []gin.HandlerFunc) {
+	// 添加路由中间件
+	ginHandlers = append(ginHandlers, c.config.RestoreMiddlewares...)
+	// 添加实际路由执行函数
+	ginHandlers = append(
+		ginHandlers,
+		func(ginCtx *gin.Context) {
+			// 实例化核心对象
+			core := NewCore[T](
+				ginCtx, c.GetModel,
+				c.config.BeforeRestore, c.config.AfterRestore,
+				getModelMeta(c.GetModel().TableName()).Rules["update"],
+			)
+			core.config, core.parent = &c.config, c.parent
+			// 执行批量恢复函数
+			core.BulkRestore()
+			// 如果有错误，组织错误响应
+			if core.err != nil {
+				HandleErr(ginCtx, core.err)
 				return
 			}
 		})
@@ -0,0 +1,139 @@
+package crud
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// listCursor 游标分页中游标的内容，对外是 base64 编码后的不透明字符串
+type listCursor struct {
+	// Sort 生成游标时使用的排序，游标只能用于相同排序的查询
+	Sort string `json:"s"`
+	// Values 当前页边界行的排序字段值，最后一个值是 id
+	Values []interface{} `json:"v"`
+	// Prev 是否是向前翻页的游标
+	Prev bool `json:"p,omitempty"`
+}
+
+// cursorSorts 游标分页使用的排序，在排序字段后补充 id 保证排序结果唯一
+// 边界行的排序字段为 NULL 时 keyset 条件无法匹配任何记录，分页会提前结束，所以不支持可以为空的排序字段
+func (r *RegisteredModel) cursorSorts(sorts []sortField) ([]sortField, error) {
+	keys := make([]sortField, 0, len(sorts)+1)
+	for _, s := range sorts {
+		if s.Nulls != "" {
+			return nil, fmt.Errorf("游标分页不支持指定空值位置的排序字段 %s", s.Field)
+		}
+		if field := r.fieldByJsonTag(s.Field); field != nil && isNullableType(field.Type) {
+			return nil, fmt.Errorf("游标分页不支持按可以为空的字段 %s 排序", s.Field)
+		}
+		keys = append(keys, s)
+		if s.Field == "id" {
+			return keys, nil
+		}
+	}
+	return append(keys, sortField{Field: "id"}), nil
+}
+
+// isNullableType 判断字段是否可以为空，包括指针、sql.Null* 以及 gorm.DeletedAt 类型
+func isNullableType(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr || t == deletedAtType {
+		return true
+	}
+	return t.PkgPath() == "database/sql" && strings.HasPrefix(t.Name(), "Null")
+}
+
+// sortSignature 排序的签名，例如 -created_at,id
+func sortSignature(sorts []sortField) string {
+	parts := make([]string, 0, len(sorts))
+	for _, s := range sorts {
+		if s.Desc {
+			parts = append(parts, "-"+s.Field)
+		} else {
+			parts = append(parts, s.Field)
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// encodeCursor 根据边界行生成游标
+func encodeCursor(sorts []sortField, row map[string]interface{}, prev bool) string {
+	cursor := listCursor{Sort: sortSignature(sorts), Prev: prev}
+	for _, s := range sorts {
+		value := row[s.Field]
+		// 部分数据库驱动会将字符串扫描为 []byte，直接序列化会变成 base64
+		if b, ok := value.([]byte); ok {
+			value = string(b)
+		}
+		cursor.Values = append(cursor.Values, value)
+	}
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor 解析游标，并将游标中的值转换为排序字段对应的类型
+func (r *RegisteredModel) decodeCursor(raw string, sorts []sortField) (*listCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, errors.New("游标格式错误")
+	}
+
+	var cursor listCursor
+	decoder := json.NewDecoder(strings.NewReader(string(data)))
+	decoder.UseNumber()
+	if err := decoder.Decode(&cursor); err != nil {
+		return nil, errors.New("游标格式错误")
+	}
+	if cursor.Sort != sortSignature(sorts) {
+		return nil, errors.New("游标与当前查询的排序不一致")
+	}
+	if len(cursor.Values) != len(sorts) {
+		return nil, errors.New("游标格式错误")
+	}
+
+	for i, s := range sorts {
+		field := r.fieldByJsonTag(s.Field)
+		if field == nil {
+			continue
+		}
+		value, err := convertFilterValue(filterFieldType(field.Type), cursor.Values[i])
+		if err != nil {
+			return nil, fmt.Errorf("游标中字段 %s 的值无效", s.Field)
+		}
+		cursor.Values[i] = value
+	}
+	return &cursor, nil
+}
+
+// keysetCondition 生成游标之后（按排序方向）所有行的查询条件
+// 例如排序为 -created_at,id 时生成 (created_at < ?) OR (created_at = ? AND id > ?)
+func keysetCondition(sorts []sortField, values []interface{}) (query string, args []interface{}) {
+	ors := make([]string, 0, len(sorts))
+	for i, s := range sorts {
+		ands := make([]string, 0, i+1)
+		for j := 0; j < i; j++ {
+			ands = append(ands, fmt.Sprintf("%s = ?", sorts[j].Field))
+			args = append(args, values[j])
+		}
+		if s.Desc {
+			ands = append(ands, fmt.Sprintf("%s < ?", s.Field))
+		} else {
+			ands = append(ands, fmt.Sprintf("%s > ?", s.Field))
+		}
+		args = append(args, values[i])
+		ors = append(ors, "("+strings.Join(ands, " AND ")+")")
+	}
+	return strings.Join(ors, " OR "), args
+}
+
+// reverseSorts 反转排序方向，用于向前翻页
+func reverseSorts(sorts []sortField) []sortField {
+	reversed := make([]sortField, len(sorts))
+	for i, s := range sorts {
+		reversed[i] = sortField{Field: s.Field, Desc: !s.Desc}
+	}
+	return reversed
+}
@@ -0,0 +1,175 @@
+package crud
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/polaris0915/go-crud/cError"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	meta := newFilterTestMeta()
+	keys, err := meta.cursorSorts([]sortField{{Field: "created_at", Desc: true}, {Field: "score"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sortSignature(keys) != "-created_at,score,id" {
+		t.Fatalf("keys = %s", sortSignature(keys))
+	}
+
+	createdAt := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	row := map[string]interface{}{"created_at": createdAt, "score": 1.5, "id": uint64(12), "status": "open"}
+	raw := encodeCursor(keys, row, true)
+	cursor, err := meta.decodeCursor(raw, keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []interface{}{createdAt, 1.5, uint64(12)}
+	if !cursor.Prev || !reflect.DeepEqual(cursor.Values, want) {
+		t.Errorf("decoded = %+v, want prev cursor with %v", cursor, want)
+	}
+
+	// 游标只能用于生成时的排序
+	if _, err := meta.decodeCursor(raw, []sortField{{Field: "created_at"}, {Field: "score"}, {Field: "id"}}); err == nil {
+		t.Error("mismatched sort: expected error")
+	}
+	tampered := func(cursor string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(cursor))
+	}
+	for _, raw := range []string{
+		"not base64!",
+		tampered("not json"),
+		tampered(`{"s":"-created_at,score,id","v":[1.5,12]}`),
+		tampered(`{"s":"-created_at,score,id","v":["yesterday",1.5,12]}`),
+		tampered(`{"s":"-created_at,score,id","v":["2026-01-02T03:04:05Z",1.5,"x"]}`),
+	} {
+		if _, err := meta.decodeCursor(raw, keys); err == nil {
+			t.Errorf("%q: expected error", raw)
+		}
+	}
+}
+
+func TestCursorSorts(t *testing.T) {
+	meta := newFilterTestMeta()
+	tests := []struct {
+		sorts []sortField
+		want  string
+		ok    bool
+	}{
+		{nil, "id", true},
+		{[]sortField{{Field: "id", Desc: true}, {Field: "status"}}, "-id", true},
+		{[]sortField{{Field: "status"}}, "status,id", true},
+		{[]sortField{{Field: "status", Nulls: nullsLast}}, "", false},
+		// closed_at 是指针类型，边界行为 NULL 时无法生成 keyset 条件
+		{[]sortField{{Field: "closed_at"}}, "", false},
+	}
+	for _, tt := range tests {
+		keys, err := meta.cursorSorts(tt.sorts)
+		if (err == nil) != tt.ok || (tt.ok && sortSignature(keys) != tt.want) {
+			t.Errorf("%+v: keys = %s, err = %v", tt.sorts, sortSignature(keys), err)
+		}
+	}
+}
+
+func TestKeysetCondition(t *testing.T) {
+	query, args := keysetCondition([]sortField{{Field: "created_at", Desc: true}, {Field: "id"}}, []interface{}{"2026-01-01", 12})
+	if query != "(created_at < ?) OR (created_at = ? AND id > ?)" || fmt.Sprint(args) != "[2026-01-01 2026-01-01 12]" {
+		t.Errorf("query = %s, args = %v", query, args)
+	}
+}
+
+type cursorTask struct {
+	ID       uint64     `gorm:"column:id;primary_key" json:"id" crud:"allow_get"`
+	Priority int        `gorm:"column:priority" json:"priority" crud:"allow_get"`
+	DueAt    *time.Time `gorm:"column:due_at" json:"due_at" crud:"allow_get"`
+}
+
+func (s *cursorTask) TableName() string { return "cursor_task" }
+
+func TestListCursor(t *testing.T) {
+	r, db := newTestServer(t, &cursorTask{})
+	RegisterModelApi[*cursorTask](r.Group("/api"), "task")
+
+	// priority 有重复值，相同 priority 的记录按 id 排序
+	for i, priority := range []int{3, 1, 2, 1, 3, 1, 2} {
+		db.Create(&cursorTask{ID: uint64(i + 1), Priority: priority})
+	}
+
+	type listResponse struct {
+		Code int `json:"code"`
+		Data struct {
+			Data []struct {
+				ID uint64 `json:"id"`
+			} `json:"data"`
+			Pagination struct {
+				NextCursor string `json:"next_cursor"`
+				PrevCursor string `json:"prev_cursor"`
+			} `json:"pagination"`
+		} `json:"data"`
+	}
+	list := func(t *testing.T, query url.Values) (listResponse, string) {
+		t.Helper()
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/task?"+query.Encode(), nil))
+		var resp listResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("%s: %v", query.Encode(), err)
+		}
+		var ids []uint64
+		for _, item := range resp.Data.Data {
+			ids = append(ids, item.ID)
+		}
+		return resp, fmt.Sprint(ids)
+	}
+
+	t.Run("pages", func(t *testing.T) {
+		query := url.Values{"sort": {"-priority"}, "limit": {"3"}, "fields": {"id"}}
+		pages := []string{"[1 5 3]", "[7 2 4]", "[6]"}
+		var cursors []listResponse
+		for i, want := range pages {
+			resp, ids := list(t, query)
+			if ids != want {
+				t.Fatalf("page %d = %s, want %s", i+1, ids, want)
+			}
+			if (resp.Data.Pagination.PrevCursor == "") != (i == 0) || (resp.Data.Pagination.NextCursor == "") != (i == len(pages)-1) {
+				t.Errorf("page %d pagination = %+v", i+1, resp.Data.Pagination)
+			}
+			cursors = append(cursors, resp)
+			query.Set("cursor", resp.Data.Pagination.NextCursor)
+		}
+
+		// 从最后一页向前翻页回到第一页
+		for i := len(pages) - 2; i >= 0; i-- {
+			query.Set("cursor", cursors[i+1].Data.Pagination.PrevCursor)
+			resp, ids := list(t, query)
+			if ids != pages[i] {
+				t.Errorf("prev page %d = %s, want %s", i+1, ids, pages[i])
+			}
+			if (resp.Data.Pagination.PrevCursor == "") != (i == 0) || resp.Data.Pagination.NextCursor == "" {
+				t.Errorf("prev page %d pagination = %+v", i+1, resp.Data.Pagination)
+			}
+		}
+	})
+
+	t.Run("rejected", func(t *testing.T) {
+		resp, _ := list(t, url.Values{"sort": {"-priority"}, "limit": {"3"}})
+		next := resp.Data.Pagination.NextCursor
+		tests := []url.Values{
+			{"sort": {"priority"}, "cursor": {next}},
+			{"sort": {"-priority"}, "cursor": {next[:len(next)-2]}},
+			{"sort": {"due_at"}, "limit": {"3"}},
+		}
+		for _, query := range tests {
+			if resp, _ := list(t, query); resp.Code != cError.ErrReadPagination {
+				t.Errorf("%s: code = %d, want %d", query.Encode(), resp.Code, cError.ErrReadPagination)
+			}
+		}
+	})
+}
@@ -5,14 +5,16 @@ import (
 	"fmt"
 	"github.com/polaris0915/go-crud/cError"
 	"github.com/polaris0915/go-crud/model"
-	"github.com/spf13/cast"
 	"gorm.io/gorm"
 	"net/http"
 )
 
 func (c *Core[T]) Delete() {
-	// 1. 解析路径参数，获取资源ID
-	id := cast.ToUint64(c.ginCtx.Param("id"))
+	// 1. 解析路径参数，获取资源ID，嵌套路由检查父资源是否存在
+	if c.resolveParent(); c.err != nil {
+		return
+	}
+	id := c.resourceID()
 	if id == 0 {
 		c.err = cError.New(cError.ErrDeleteMissingField, nil, errors.New("缺少资源ID字段信息"))
 		return
@@ -23,15 +25,21 @@ func (c *Core[T]) Delete() {
 	db := model.Use()
 
 	// 查询记录是否存在
-	result := db.First(&jsonModel, id)
+	result := c.scoped(db).First(&jsonModel, id)
 	if result.Error != nil {
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
-			c.err = cError.New(cError.ErrDeleteNotFound, nil, fmt.Errorf("ID: %s的资源不存在", id))
+			c.err = cError.New(cError.ErrDeleteNotFound, nil, fmt.Errorf("ID: %d的资源不存在", id))
 		} else {
 			c.err = cError.New(cError.ErrDBQuery, nil, result.Error)
 		}
 		return
 	}
+	// 检查 If-Match 请求头与记录当前的版本是否一致
+	modelMeta := getModelMeta(jsonModel.TableName())
+	version := c.checkIfMatch(modelMeta, jsonModel)
+	if c.err != nil {
+		return
+	}
 
 	// 3. 执行前置钩子（可用于权限检查和业务规则验证）
 	if c.beforeHook != nil {
@@ -52,19 +60,22 @@ func (c *Core[T]) Delete() {
 		defer func() {
 			if c.err != nil {
 				db.Rollback()
-			} else {
-				db.Commit()
 			}
 		}()
 	}
 
 	// 5. 执行删除操作（软删除）
 	// 假设模型已经实现了gorm.Model或包含DeletedAt字段
-	result = db.Delete(&jsonModel)
+	// 模型有版本字段时只删除版本没有变化的记录
+	result = versionCondition(db, modelMeta, version).Delete(&jsonModel)
 	if result.Error != nil {
 		c.err = cError.New(cError.ErrDeleteGeneral, nil, result.Error)
 		return
 	}
+	if version != nil && result.RowsAffected == 0 {
+		c.err = c.currentVersionConflict(modelMeta, db, id)
+		return
+	}
 
 	if result.RowsAffected == 0 {
 		// 这种情况通常不会发生，因为我们已经检查了记录是否存在
@@ -72,6 +83,10 @@ func (c *Core[T]) Delete() {
 		c.err = cError.New(cError.ErrDeleteGeneral, nil, errors.New("删除操作未影响任何记录"))
 		return
 	}
+	// 开启事务时在提交之后使缓存失效
+	if !c.enableTransaction {
+		invalidateModel(jsonModel.TableName())
+	}
 
 	// 6. 执行后置钩子（可用于清理相关资源、发送通知等）
 	if c.afterHook != nil {
@@ -81,6 +96,15 @@ func (c *Core[T]) Delete() {
 		}
 	}
 
+	// 提交事务，在提交之后使缓存失效，避免并发的查询在提交之前读到旧数据并重新写入缓存
+	if c.enableTransaction {
+		if err := db.Commit().Error; err != nil {
+			c.err = cError.New(cError.ErrDBTransaction, nil, err)
+			return
+		}
+		invalidateModel(jsonModel.TableName())
+	}
+
 	// 7. 返回结果
 	HandleRes(c.ginCtx, http.StatusNoContent, true, "")
 }
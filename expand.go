@@ -0,0 +1,390 @@
+package crud
+
+import (
+	"errors"
+	"fmt"
+	"github.com/polaris0915/go-crud/model"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// defaultMaxExpandDepth 没有配置时关联展开的最大深度
+const defaultMaxExpandDepth = 3
+
+// maxExpandLimit 列表关联每条记录最多返回的关联数据数量
+const maxExpandLimit = 100
+
+// 查询关联数据时使用的辅助列，返回前删除
+const (
+	expandKeyColumn       = "crud_expand_key"
+	expandRowNumberColumn = "crud_row_number"
+)
+
+// expandNode 关联展开参数解析之后的节点
+// 例如 author(fields:id,name).department 解析为 author 节点，department 为其子节点
+type expandNode struct {
+	Relation string
+	// Fields 关联表返回的字段，为空时返回所有 allow_get 字段
+	Fields []string
+	// Sort 列表关联的排序，Limit 列表关联每条记录最多返回的数量，为0时不限制
+	Sort     []sortField
+	Limit    int
+	Children []*expandNode
+}
+
+// parseExpand 解析关联展开参数
+// 多个关联之间使用逗号分隔，使用 . 继续展开关联表的关联
+// 使用 (fields:a,b) 指定关联表返回的字段，列表关联还可以使用 sort 和 limit 参数
+// 例如 author(fields:id,name).department,files(sort:-created_at,limit:5)
+// 相同路径的关联会合并为一个节点，指定的返回字段取并集
+func parseExpand(param string) ([]*expandNode, error) {
+	items, err := splitTopLevel(param, ',')
+	if err != nil {
+		return nil, err
+	}
+
+	var nodes []*expandNode
+	for _, item := range items {
+		if item = strings.TrimSpace(item); item == "" {
+			continue
+		}
+		segments, err := splitTopLevel(item, '.')
+		if err != nil {
+			return nil, err
+		}
+
+		level := &nodes
+		for _, segment := range segments {
+			node, err := parseExpandSegment(strings.TrimSpace(segment))
+			if err != nil {
+				return nil, err
+			}
+			i := slices.IndexFunc(*level, func(n *expandNode) bool { return n.Relation == node.Relation })
+			if i < 0 {
+				*level = append(*level, node)
+				i = len(*level) - 1
+			}
+			existing := (*level)[i]
+			for _, field := range node.Fields {
+				if !slices.Contains(existing.Fields, field) {
+					existing.Fields = append(existing.Fields, field)
+				}
+			}
+			if len(node.Sort) > 0 {
+				existing.Sort = node.Sort
+			}
+			if node.Limit > 0 {
+				existing.Limit = node.Limit
+			}
+			level = &existing.Children
+		}
+	}
+	return nodes, nil
+}
+
+// expandOptions 关联展开支持的参数
+var expandOptions = []string{"fields", "sort", "limit"}
+
+// parseExpandSegment 解析单个关联，例如 role、role(fields:id,role) 或者 files(fields:id,file_name,sort:-id,limit:5)
+// 括号中的参数同样使用逗号分隔，以参数名加冒号开头的项开始一个新的参数
+func parseExpandSegment(segment string) (*expandNode, error) {
+	name, options, hasOptions := strings.Cut(segment, "(")
+	node := &expandNode{Relation: strings.TrimSpace(name)}
+	if node.Relation == "" {
+		return nil, fmt.Errorf("关联展开参数 %q 中关联名称不能为空", segment)
+	}
+	if !hasOptions {
+		return node, nil
+	}
+
+	options, ok := strings.CutSuffix(strings.TrimSpace(options), ")")
+	if !ok {
+		return nil, fmt.Errorf("关联 %s 的参数格式错误", node.Relation)
+	}
+	values := make(map[string][]string)
+	var current string
+	for _, item := range strings.Split(options, ",") {
+		if key, value, ok := strings.Cut(item, ":"); ok && slices.Contains(expandOptions, strings.TrimSpace(key)) {
+			current = strings.TrimSpace(key)
+			if _, ok := values[current]; ok {
+				return nil, fmt.Errorf("关联 %s 的 %s 参数重复", node.Relation, current)
+			}
+			values[current], item = nil, value
+		} else if current == "" {
+			return nil, fmt.Errorf("关联 %s 只支持 %s 参数", node.Relation, strings.Join(expandOptions, "、"))
+		}
+		if item = strings.TrimSpace(item); item != "" {
+			values[current] = append(values[current], item)
+		}
+	}
+
+	for key, items := range values {
+		if len(items) == 0 {
+			return nil, fmt.Errorf("关联 %s 的 %s 不能为空", node.Relation, key)
+		}
+		switch key {
+		case "fields":
+			node.Fields = items
+		case "sort":
+			for _, item := range items {
+				sort, err := parseSortField(item)
+				if err != nil {
+					return nil, err
+				}
+				node.Sort = append(node.Sort, sort)
+			}
+		case "limit":
+			limit, err := strconv.Atoi(items[0])
+			if len(items) > 1 || err != nil || limit < 1 || limit > maxExpandLimit {
+				return nil, fmt.Errorf("关联 %s 的 limit 只能是 1 到 %d 之间的整数", node.Relation, maxExpandLimit)
+			}
+			node.Limit = limit
+		}
+	}
+	return node, nil
+}
+
+// splitTopLevel 按照分隔符拆分字符串，括号内的分隔符不拆分
+func splitTopLevel(s string, sep byte) ([]string, error) {
+	var parts []string
+	depth, start := 0, 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '(':
+			depth++
+		case ')':
+			if depth--; depth < 0 {
+				return nil, fmt.Errorf("关联展开参数第 %d 个字符的括号不匹配", i+1)
+			}
+		case sep:
+			if depth == 0 {
+				parts = append(parts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	if depth != 0 {
+		return nil, errors.New("关联展开参数中的括号没有闭合")
+	}
+	return append(parts, s[start:]), nil
+}
+
+// checkExpand 检查关联展开参数
+// 包括关联关系是否存在、关联表返回的字段是否是 allow_get 字段以及展开深度
+// 同一个模型可以在展开路径中重复出现（例如自关联），递归展开由深度限制
+func (r *RegisteredModel) checkExpand(nodes []*expandNode, maxDepth int) error {
+	return r.checkExpandPath(nodes, []string{r.ModelName}, maxDepth)
+}
+
+// checkExpandPath path 为当前已经展开的模型，第一个是查询的主模型
+func (r *RegisteredModel) checkExpandPath(nodes []*expandNode, path []string, maxDepth int) error {
+	for _, node := range nodes {
+		a, ok := r.Associations[node.Relation]
+		if !ok {
+			return fmt.Errorf("关联 %s 不存在", node.Relation)
+		}
+		if len(path) > maxDepth {
+			return fmt.Errorf("关联展开深度不能超过 %d", maxDepth)
+		}
+		current := append(slices.Clip(path), a.Table)
+
+		relationMeta := getModelMeta(a.Table)
+		if relationMeta == nil {
+			return fmt.Errorf("关联表 %s 没有注册", a.Table)
+		}
+		for _, field := range node.Fields {
+			if _, ok := relationMeta.AllowGetFields[field]; !ok {
+				return fmt.Errorf("关联表 %s 不允许获取字段 %s", a.Table, field)
+			}
+		}
+		if len(relationMeta.AllowGetFields) == 0 {
+			return fmt.Errorf("关联表 %s 中没有可查询字段", a.Table)
+		}
+		if (len(node.Sort) > 0 || node.Limit > 0) && !a.many() {
+			return fmt.Errorf("关联 %s 不是列表关联，不支持 sort 和 limit 参数", node.Relation)
+		}
+		for _, sort := range node.Sort {
+			if _, ok := relationMeta.AllowGetFields[sort.Field]; !ok {
+				return fmt.Errorf("关联表 %s 不允许按字段 %s 排序", a.Table, sort.Field)
+			}
+		}
+
+		if err := relationMeta.checkExpandPath(node.Children, current, maxDepth); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// relationColumns 获取展开关联数据需要用到的当前表的列，调用前需要先通过 checkExpand 检查
+func (r *RegisteredModel) relationColumns(nodes []*expandNode) []string {
+	columns := make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		columns = append(columns, r.Associations[node.Relation].OwnColumn)
+	}
+	return columns
+}
+
+// expandRelations 批量加载关联数据并写入到每条记录中
+// 每个关联只执行一次 IN 查询，关联表的关联按层递归处理
+// 记录中需要包含关联用到的列，没有关联数据时单个关联的值为 nil，列表关联的值为空列表
+func (r *RegisteredModel) expandRelations(results []map[string]interface{}, nodes []*expandNode) error {
+	for _, node := range nodes {
+		a := r.Associations[node.Relation]
+
+		// 收集当前页所有记录的匹配值，去重之后一次查询
+		keys := make([]interface{}, 0, len(results))
+		seen := make(map[string]struct{}, len(results))
+		for _, result := range results {
+			value := result[a.OwnColumn]
+			key := relationKey(value)
+			if key == "" {
+				continue
+			}
+			if _, ok := seen[key]; ok {
+				continue
+			}
+			seen[key] = empty
+			keys = append(keys, value)
+		}
+
+		related := make(map[string][]map[string]interface{})
+		if len(keys) > 0 {
+			rows, err := getRelatedRows(a, node, keys)
+			if err != nil {
+				return err
+			}
+			related = rows
+		}
+
+		for _, result := range results {
+			rows := related[relationKey(result[a.OwnColumn])]
+			switch {
+			case a.many():
+				if rows == nil {
+					rows = []map[string]interface{}{}
+				}
+				result[node.Relation] = rows
+			case len(rows) > 0:
+				result[node.Relation] = rows[0]
+			default:
+				result[node.Relation] = nil
+			}
+		}
+	}
+	return nil
+}
+
+// getRelatedRows 根据匹配值查询关联表的数据并展开下一层关联，返回按匹配值分组的记录
+// many2many 通过中间表连接查询，指定 limit 时使用窗口函数限制每条记录的关联数据数量
+func getRelatedRows(a *association, node *expandNode, keys []interface{}) (map[string][]map[string]interface{}, error) {
+	modelMeta := getModelMeta(a.Table)
+	if modelMeta == nil {
+		return nil, fmt.Errorf("关联表 %s 没有注册", a.Table)
+	}
+
+	// 没有指定返回字段时返回关联表中所有允许查询的字段
+	fields := slices.Clone(node.Fields)
+	if len(fields) == 0 {
+		for field := range modelMeta.AllowGetFields {
+			fields = append(fields, field)
+		}
+	}
+	if len(fields) == 0 {
+		return nil, errors.New("关联表中没有可查询字段")
+	}
+
+	// 展开下一层关联需要用到的列，没有选择的列在返回前删除
+	var extraFields []string
+	for _, column := range modelMeta.relationColumns(node.Children) {
+		if !slices.Contains(fields, column) {
+			fields = append(fields, column)
+			extraFields = append(extraFields, column)
+		}
+	}
+	selects := make([]string, 0, len(fields)+2)
+	for _, field := range fields {
+		selects = append(selects, a.Table+"."+field)
+	}
+
+	db := model.Use().Table(a.Table)
+	keyColumn, typeTable := a.Table+"."+a.RelatedColumn, a.Table
+	if a.JoinTable != "" {
+		db = db.Joins(fmt.Sprintf("JOIN %s ON %s.%s = %s.%s",
+			a.JoinTable, a.JoinTable, a.JoinRelatedColumn, a.Table, a.RelatedColumn))
+		keyColumn, typeTable = a.JoinTable+"."+a.JoinOwnColumn, a.JoinTable
+	}
+	selects = append(selects, keyColumn+" AS "+expandKeyColumn)
+	db = db.Where(keyColumn+" IN ?", keys)
+	// 不展开软删除的关联记录
+	if related := getModelMeta(a.Table); related != nil {
+		db = withDeleted(db, related, a.Table, deletedExclude)
+	}
+	if a.PolymorphicColumn != "" {
+		db = db.Where(fmt.Sprintf("%s.%s = ?", typeTable, a.PolymorphicColumn), a.PolymorphicValue)
+	}
+
+	var orders []string
+	for _, sort := range node.Sort {
+		sort.Field = a.Table + "." + sort.Field
+		orders = append(orders, sort.orderClauses()...)
+	}
+	if a.many() {
+		orders = append(orders, a.Table+".id asc")
+	}
+	if node.Limit > 0 {
+		selects = append(selects, fmt.Sprintf("ROW_NUMBER() OVER (PARTITION BY %s ORDER BY %s) AS %s",
+			keyColumn, strings.Join(orders, ", "), expandRowNumberColumn))
+		db = model.Use().Table("(?) AS expand_rows", db.Select(selects)).
+			Where(expandRowNumberColumn+" <= ?", node.Limit).Order(expandRowNumberColumn)
+	} else {
+		db = db.Select(selects)
+		for _, order := range orders {
+			db = db.Order(order)
+		}
+	}
+
+	var rows []map[string]interface{}
+	if err := db.Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	if err := modelMeta.expandRelations(rows, node.Children); err != nil {
+		return nil, err
+	}
+
+	data := make(map[string][]map[string]interface{})
+	for _, row := range rows {
+		key := relationKey(row[expandKeyColumn])
+		delete(row, expandKeyColumn)
+		delete(row, expandRowNumberColumn)
+		for _, field := range extraFields {
+			delete(row, field)
+		}
+		data[key] = append(data[key], row)
+	}
+	return data, nil
+}
+
+// relationKey 将不同数据库驱动返回的id统一为字符串，用于关联数据的匹配，空值或者0返回空字符串
+func relationKey(id interface{}) string {
+	switch v := id.(type) {
+	case nil:
+		return ""
+	case []byte:
+		id = string(v)
+	}
+	key := fmt.Sprint(id)
+	if key == "0" || key == "" {
+		return ""
+	}
+	return key
+}
+
+// maxExpandDepth 关联展开的最大深度，没有配置时使用默认值
+func (c *Core[T]) maxExpandDepth() int {
+	if c.config != nil && c.config.MaxExpandDepth > 0 {
+		return c.config.MaxExpandDepth
+	}
+	return defaultMaxExpandDepth
+}
@@ -0,0 +1,338 @@
+package crud
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/polaris0915/go-crud/model"
+)
+
+// setupExpandTest 初始化内存数据库，写入3个文件业务类型以及100个文件
+// 返回的计数器记录初始化之后执行的查询次数
+func setupExpandTest(t *testing.T) (*gin.Engine, *int64) {
+	t.Helper()
+	r, db := newTestServer(t, &model.RelateType{}, &model.File{})
+
+	for i := 1; i <= 3; i++ {
+		db.Create(&model.RelateType{ID: uint64(i), Type: fmt.Sprintf("type%d", i)})
+	}
+	for i := 1; i <= 100; i++ {
+		db.Create(&model.File{
+			ID:           uint64(i),
+			FileName:     fmt.Sprintf("file%03d", i),
+			DisplayName:  fmt.Sprintf("file%03d", i),
+			FilePath:     fmt.Sprintf("/storage/file%03d", i),
+			RelateTypeID: uint64(i%3 + 1),
+		})
+	}
+
+	RegisterModelApi[*model.File](r.Group("/api"), "file")
+	return r, countQueries(t, db)
+}
+
+func doExpandRequest(t *testing.T, r *gin.Engine, url string, out interface{}) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, url, nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET %s: status %d, body %s", url, w.Code, w.Body.String())
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), out); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGetListExpandBatchesQueries(t *testing.T) {
+	r, queries := setupExpandTest(t)
+
+	var res struct {
+		Data struct {
+			Data []map[string]interface{} `json:"data"`
+		} `json:"data"`
+	}
+	atomic.StoreInt64(queries, 0)
+	// 重复的关联表只会查询一次
+	doExpandRequest(t, r, "/api/file?per_page=100&fields=file_name&expand=relate_type,relate_type", &res)
+
+	// COUNT + 分页查询 + 关联表查询
+	if n := atomic.LoadInt64(queries); n != 3 {
+		t.Errorf("expected 3 queries, got %d", n)
+	}
+	if len(res.Data.Data) != 100 {
+		t.Fatalf("expected 100 rows, got %d", len(res.Data.Data))
+	}
+	for _, row := range res.Data.Data {
+		if _, ok := row["relate_type_id"]; ok {
+			t.Fatalf("foreign key not requested but returned: %v", row)
+		}
+		relateType, ok := row["relate_type"].(map[string]interface{})
+		if !ok {
+			t.Fatalf("relate_type not expanded: %v", row)
+		}
+		var i int
+		fmt.Sscanf(row["file_name"].(string), "file%03d", &i)
+		if want := fmt.Sprintf("type%d", i%3+1); relateType["type"] != want {
+			t.Errorf("%s: expected relate_type %s, got %v", row["file_name"], want, relateType["type"])
+		}
+	}
+}
+
+func TestGetExpandQueries(t *testing.T) {
+	r, queries := setupExpandTest(t)
+
+	var res struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	atomic.StoreInt64(queries, 0)
+	doExpandRequest(t, r, "/api/file/5?fields=file_name&expand=relate_type", &res)
+
+	if n := atomic.LoadInt64(queries); n != 2 {
+		t.Errorf("expected 2 queries, got %d", n)
+	}
+	relateType, ok := res.Data["relate_type"].(map[string]interface{})
+	if !ok || relateType["type"] != "type3" {
+		t.Errorf("unexpected relate_type: %v", res.Data)
+	}
+}
+
+func TestParseExpand(t *testing.T) {
+	nodes, err := parseExpand("author(fields:id, name).department,files(fields:file_name,sort:-created_at,id,limit:5),author(fields:email)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*expandNode{
+		{
+			Relation: "author",
+			Fields:   []string{"id", "name", "email"},
+			Children: []*expandNode{{Relation: "department"}},
+		},
+		{
+			Relation: "files",
+			Fields:   []string{"file_name"},
+			Sort:     []sortField{{Field: "created_at", Desc: true}, {Field: "id"}},
+			Limit:    5,
+		},
+	}
+	if !reflect.DeepEqual(nodes, want) {
+		got, _ := json.Marshal(nodes)
+		t.Errorf("unexpected nodes %s", got)
+	}
+
+	for _, param := range []string{
+		"author(fields:id", "author)", "author..department", "author(fields:)", "author(foo:1)", "(fields:id)",
+		"files(limit:0)", "files(limit:101)", "files(limit:1,2)", "files(fields:id,fields:name)",
+	} {
+		if _, err := parseExpand(param); err == nil {
+			t.Errorf("%q: expected error", param)
+		}
+	}
+}
+
+type expandDepartment struct {
+	ID   uint64 `gorm:"column:id;primary_key" json:"id" crud:"allow_get"`
+	Name string `gorm:"column:name" json:"name" crud:"allow_get"`
+	// AuthorID 部门负责人
+	AuthorID uint64        `gorm:"column:author_id" json:"author_id" crud:"allow_get"`
+	Author   *expandAuthor `gorm:"foreignKey:AuthorID" json:"author"`
+}
+
+func (d *expandDepartment) TableName() string { return "department" }
+
+type expandAuthor struct {
+	ID           uint64            `gorm:"column:id;primary_key" json:"id" crud:"allow_get"`
+	Name         string            `gorm:"column:name" json:"name" crud:"allow_get"`
+	Email        string            `gorm:"column:email" json:"email"`
+	DepartmentID uint64            `gorm:"column:department_id" json:"department_id" crud:"allow_get"`
+	Department   *expandDepartment `gorm:"foreignKey:DepartmentID" json:"department"`
+}
+
+func (a *expandAuthor) TableName() string { return "author" }
+
+type expandBook struct {
+	ID       uint64        `gorm:"column:id;primary_key" json:"id" crud:"allow_get"`
+	Title    string        `gorm:"column:title" json:"title" crud:"allow_get"`
+	AuthorID uint64        `gorm:"column:author_id" json:"author_id" crud:"allow_get"`
+	Author   *expandAuthor `gorm:"foreignKey:AuthorID" json:"author"`
+}
+
+func (b *expandBook) TableName() string { return "book" }
+
+func TestGetListNestedExpand(t *testing.T) {
+	r, db := newTestServer(t, &expandDepartment{}, &expandAuthor{}, &expandBook{})
+
+	db.Create(&expandDepartment{ID: 1, Name: "dev", AuthorID: 1})
+	db.Create(&expandAuthor{ID: 1, Name: "alice", Email: "alice@example.com", DepartmentID: 1})
+	db.Create(&expandAuthor{ID: 2, Name: "bob", Email: "bob@example.com"})
+	for i := 1; i <= 10; i++ {
+		db.Create(&expandBook{ID: uint64(i), Title: fmt.Sprintf("book%02d", i), AuthorID: uint64(i%2 + 1)})
+	}
+
+	queries := countQueries(t, db)
+
+	RegisterModelApi[*expandBook](r.Group("/api"), "book")
+	RegisterModelApi[*expandBook](r.Group("/shallow"), "book", MaxExpandDepth(1))
+
+	var res struct {
+		Data struct {
+			Data []map[string]interface{} `json:"data"`
+		} `json:"data"`
+	}
+	doExpandRequest(t, r, "/api/book?sort=id&fields=title&expand=author(fields:name).department(fields:name)", &res)
+	// COUNT + 分页查询 + 每层关联各一次
+	if n := atomic.LoadInt64(queries); n != 4 {
+		t.Errorf("expected 4 queries, got %d", n)
+	}
+	if len(res.Data.Data) != 10 {
+		t.Fatalf("expected 10 rows, got %d", len(res.Data.Data))
+	}
+	for i, row := range res.Data.Data {
+		// 奇数编号的书作者为 bob，没有部门；偶数编号的书作者为 alice，属于 dev 部门
+		want := `{"author":{"department":null,"name":"bob"},"title":"book%02d"}`
+		if (i+1)%2 == 0 {
+			want = `{"author":{"department":{"name":"dev"},"name":"alice"},"title":"book%02d"}`
+		}
+		data, _ := json.Marshal(row)
+		if want = fmt.Sprintf(want, i+1); string(data) != want {
+			t.Errorf("got %s\nwant %s", data, want)
+		}
+	}
+
+	// 同一个模型可以在展开路径中重复出现，由深度限制递归
+	var book struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	doExpandRequest(t, r, "/api/book/2?fields=title&expand=author(fields:name).department(fields:name).author(fields:name)", &book)
+	want := `{"author":{"department":{"author":{"name":"alice"},"name":"dev"},"name":"alice"},"title":"book02"}`
+	if data, _ := json.Marshal(book.Data); string(data) != want {
+		t.Errorf("got %s\nwant %s", data, want)
+	}
+
+	for _, url := range []string{
+		// 只允许获取 allow_get 字段
+		"/api/book?expand=author(fields:email)",
+		// 关联表的关联关系不存在
+		"/api/book?expand=author.book",
+		// 超过最大深度
+		"/shallow/book?expand=author.department",
+	} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, url, nil))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("GET %s: expected status 400, got %d, body %s", url, w.Code, w.Body.String())
+		}
+	}
+}
+
+type expandMember struct {
+	ID     uint64          `gorm:"column:id;primary_key" json:"id" crud:"allow_get"`
+	Name   string          `gorm:"column:name" json:"name" crud:"allow_get"`
+	Files  []*expandUpload `gorm:"foreignKey:UploaderID" json:"files"`
+	Roles  []*expandRole   `gorm:"many2many:member_role" json:"roles"`
+	Avatar *expandImage    `gorm:"polymorphic:Owner" json:"avatar"`
+}
+
+func (m *expandMember) TableName() string { return "member" }
+
+type expandUpload struct {
+	ID         uint64 `gorm:"column:id;primary_key" json:"id" crud:"allow_get"`
+	FileName   string `gorm:"column:file_name" json:"file_name" crud:"allow_get,partial_update"`
+	UploaderID uint64 `gorm:"column:uploader_id" json:"uploader_id" crud:"allow_get,partial_update"`
+}
+
+func (u *expandUpload) TableName() string { return "upload" }
+
+type expandRole struct {
+	ID   uint64 `gorm:"column:id;primary_key" json:"id" crud:"allow_get"`
+	Role string `gorm:"column:role" json:"role" crud:"allow_get"`
+}
+
+func (r *expandRole) TableName() string { return "role" }
+
+type expandImage struct {
+	ID        uint64 `gorm:"column:id;primary_key" json:"id" crud:"allow_get"`
+	URL       string `gorm:"column:url" json:"url" crud:"allow_get"`
+	OwnerID   uint64 `gorm:"column:owner_id" json:"owner_id"`
+	OwnerType string `gorm:"column:owner_type" json:"owner_type"`
+}
+
+func (i *expandImage) TableName() string { return "image" }
+
+func TestGetListExpandCollections(t *testing.T) {
+	r, db := newTestServer(t, &expandMember{}, &expandUpload{}, &expandRole{}, &expandImage{})
+
+	meta := getModelMeta("member")
+	wantAssociations := map[string]association{
+		"files": {Type: hasMany, Table: "upload", OwnColumn: "id", RelatedColumn: "uploader_id"},
+		"roles": {Type: many2many, Table: "role", OwnColumn: "id", RelatedColumn: "id",
+			JoinTable: "member_role", JoinOwnColumn: "expand_member_id", JoinRelatedColumn: "expand_role_id"},
+		"avatar": {Type: hasOne, Table: "image", OwnColumn: "id", RelatedColumn: "owner_id",
+			PolymorphicColumn: "owner_type", PolymorphicValue: "member"},
+	}
+	for name, want := range wantAssociations {
+		if a := meta.Associations[name]; a == nil || *a != want {
+			t.Errorf("association %s: got %+v, want %+v", name, a, want)
+		}
+	}
+
+	admin, editor := &expandRole{ID: 1, Role: "admin"}, &expandRole{ID: 2, Role: "editor"}
+	db.Create(&expandMember{ID: 1, Name: "alice", Roles: []*expandRole{admin, editor}})
+	db.Create(&expandMember{ID: 2, Name: "bob", Roles: []*expandRole{editor}})
+	db.Create(&expandMember{ID: 3, Name: "carol"})
+	for i := 1; i <= 6; i++ {
+		uploader := uint64(1)
+		if i == 6 {
+			uploader = 2
+		}
+		db.Create(&expandUpload{ID: uint64(i), FileName: fmt.Sprintf("file%d", i), UploaderID: uploader})
+	}
+	db.Create(&expandImage{ID: 1, URL: "alice.png", OwnerID: 1, OwnerType: "member"})
+	db.Create(&expandImage{ID: 2, URL: "other.png", OwnerID: 2, OwnerType: "other"})
+
+	queries := countQueries(t, db)
+
+	RegisterModelApi[*expandMember](r.Group("/api"), "member")
+
+	var res struct {
+		Data struct {
+			Data []map[string]interface{} `json:"data"`
+		} `json:"data"`
+	}
+	doExpandRequest(t, r, "/api/member?sort=id&fields=name"+
+		"&expand=files(fields:file_name,sort:-id,limit:2),roles(fields:role),avatar(fields:url)", &res)
+	// COUNT + 分页查询 + 每个关联各一次
+	if n := atomic.LoadInt64(queries); n != 5 {
+		t.Errorf("expected 5 queries, got %d", n)
+	}
+	want := []string{
+		`{"avatar":{"url":"alice.png"},"files":[{"file_name":"file5"},{"file_name":"file4"}],"name":"alice","roles":[{"role":"admin"},{"role":"editor"}]}`,
+		`{"avatar":null,"files":[{"file_name":"file6"}],"name":"bob","roles":[{"role":"editor"}]}`,
+		`{"avatar":null,"files":[],"name":"carol","roles":[]}`,
+	}
+	if len(res.Data.Data) != len(want) {
+		t.Fatalf("expected %d rows, got %d", len(want), len(res.Data.Data))
+	}
+	for i, row := range res.Data.Data {
+		if data, _ := json.Marshal(row); string(data) != want[i] {
+			t.Errorf("got %s\nwant %s", data, want[i])
+		}
+	}
+
+	for _, url := range []string{
+		// 单个关联不支持 limit
+		"/api/member?expand=avatar(limit:1)",
+		// 关联表返回的字段需要是 allow_get 字段
+		"/api/member?expand=avatar(fields:owner_id)",
+	} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, url, nil))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("GET %s: expected status 400, got %d, body %s", url, w.Code, w.Body.String())
+		}
+	}
+}
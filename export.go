@@ -0,0 +1,390 @@
+package crud
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/polaris0915/go-crud/cError"
+	"github.com/polaris0915/go-crud/model"
+	"github.com/spf13/cast"
+	"github.com/xuri/excelize/v2"
+	"gorm.io/gorm"
+	"io"
+	"slices"
+	"time"
+)
+
+// 导出格式
+const (
+	exportCSV    = "csv"
+	exportXLSX   = "xlsx"
+	exportNDJSON = "ndjson"
+)
+
+// exportContentTypes 导出格式对应的 Content-Type，同时用于解析 Accept 请求头
+var exportContentTypes = map[string]string{
+	exportCSV:    "text/csv",
+	exportXLSX:   "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	exportNDJSON: "application/x-ndjson",
+}
+
+// exportBatchSize 导出时每批从数据库读取的记录数
+var exportBatchSize = 500
+
+// exportQueryParams Export 中有特殊含义的查询参数，不会被当作字段过滤条件
+var exportQueryParams = map[string]struct{}{
+	"format": empty, "fields": empty, "sort_by": empty, "sort_order": empty, "sort": empty, "filter": empty,
+	"include_deleted": empty, "only_deleted": empty,
+}
+
+// exportWriter 按格式写入导出数据
+type exportWriter interface {
+	// writeHeader 写入表头，columns 为字段的json标签
+	writeHeader(columns []string) error
+	// writeRow 写入一行数据，values 与表头的顺序一致
+	writeRow(values []interface{}) error
+	// flush 每批数据写入之后调用，将已经写入的数据发送给客户端
+	flush() error
+	// close 所有数据写入之后调用
+	close() error
+}
+
+// exportFormat 导出格式，format 参数优先，其次根据 Accept 请求头选择，默认为 csv
+func exportFormat(ctx *gin.Context) (string, error) {
+	if format := ctx.Query("format"); format != "" {
+		if _, ok := exportContentTypes[format]; !ok {
+			return "", fmt.Errorf("format 只能是 %s、%s 或 %s", exportCSV, exportXLSX, exportNDJSON)
+		}
+		return format, nil
+	}
+	switch ctx.NegotiateFormat(
+		exportContentTypes[exportCSV], exportContentTypes[exportXLSX],
+		exportContentTypes[exportNDJSON], "application/ndjson",
+	) {
+	case exportContentTypes[exportXLSX]:
+		return exportXLSX, nil
+	case exportContentTypes[exportNDJSON], "application/ndjson":
+		return exportNDJSON, nil
+	}
+	return exportCSV, nil
+}
+
+// Export 流式导出列表查询的所有结果，过滤、排序以及字段参数与 GetList 相同
+// 使用游标（keyset）分批读取数据，每批写入之后立即发送给客户端，内存占用不随记录数增长
+func (c *Core[T]) Export() {
+	ctx := c.ginCtx
+
+	// 1. 获取模型元数据，嵌套路由检查父资源是否存在
+	modelMeta := getModelMeta(c.getModel().TableName())
+	if modelMeta == nil {
+		c.err = cError.New(cError.ErrReadGeneral, nil, errors.New("未找到模型元数据"))
+		return
+	}
+	if c.resolveParent(); c.err != nil {
+		return
+	}
+
+	// 2. 解析导出格式
+	format, err := exportFormat(ctx)
+	if err != nil {
+		c.err = cError.New(cError.ErrReadExport, err.Error(), err)
+		return
+	}
+
+	// 3. 验证字段选择，没有指定字段时按模型中的定义顺序导出所有允许获取的字段
+	fields := splitParam(ctx.Query("fields"))
+	for _, field := range fields {
+		if _, ok := modelMeta.AllowGetFields[field]; !ok {
+			c.err = cError.New(cError.ErrReadInvalidField, nil, fmt.Errorf("不允许获取字段: %s", field))
+			return
+		}
+	}
+	if len(fields) == 0 {
+		for _, field := range modelMeta.Fields {
+			if _, ok := modelMeta.AllowGetFields[field.JsonTag]; ok && !slices.Contains(fields, field.JsonTag) {
+				fields = append(fields, field.JsonTag)
+			}
+		}
+	}
+
+	// 4. 解析排序参数，排序字段之后补充 id 作为分批读取的游标
+	sorts := c.parseSortQuery()
+	if c.err != nil {
+		return
+	}
+	if sorts = c.resolveSorts(modelMeta, sorts); c.err != nil {
+		return
+	}
+	keys, err := modelMeta.cursorSorts(sorts)
+	if err != nil {
+		c.err = cError.New(cError.ErrReadSort, err.Error(), err)
+		return
+	}
+
+	// 5. 解析过滤参数
+	conditions, where := c.parseFilterParams(modelMeta, exportQueryParams)
+	if c.err != nil {
+		return
+	}
+	deleted := c.deletedQuery()
+	if c.err != nil {
+		return
+	}
+
+	// 6. 执行前置钩子
+	if c.beforeHook != nil {
+		if err := c.beforeHook(c); err != nil {
+			c.err = cError.New(cError.ErrReadHookFailure, nil, errors.New("列表查询前置钩子执行失败"))
+			return
+		}
+	}
+
+	// 7. 准备数据库查询，生成游标需要用到排序字段的值，没有选择的排序字段不会导出
+	db, _ := c.applyFilters(model.Use().Table(c.getModel().TableName()), modelMeta, conditions, where)
+	if c.err != nil {
+		return
+	}
+	db = withDeleted(db, modelMeta, "", deleted)
+	selected := append([]string{}, fields...)
+	for _, key := range keys {
+		if !slices.Contains(selected, key.Field) {
+			selected = append(selected, key.Field)
+		}
+	}
+	db = db.Select(selected)
+	for _, s := range keys {
+		for _, clause := range s.orderClauses() {
+			db = db.Order(clause)
+		}
+	}
+	// 每批查询都在 db 的基础上添加游标条件，需要使用新的会话避免条件累加
+	db = db.Session(&gorm.Session{})
+
+	// 8. 写入响应头以及表头
+	filename := fmt.Sprintf("%s.%s", c.getModel().TableName(), format)
+	ctx.Header("Content-Type", exportContentTypes[format])
+	ctx.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	w := newExportWriter(format, ctx.Writer)
+	if err := w.writeHeader(fields); err != nil {
+		c.exportFailed(err)
+		return
+	}
+
+	// 9. 分批读取并写入数据
+	var last []interface{}
+	for {
+		query := db
+		if last != nil {
+			condition, args := keysetCondition(keys, last)
+			query = query.Where(condition, args...)
+		}
+		var results []map[string]interface{}
+		if err := query.Limit(exportBatchSize).Find(&results).Error; err != nil {
+			c.exportFailed(err)
+			return
+		}
+
+		for _, result := range results {
+			values := make([]interface{}, len(fields))
+			for i, field := range fields {
+				values[i] = result[field]
+			}
+			if err := w.writeRow(values); err != nil {
+				c.exportFailed(err)
+				return
+			}
+		}
+		if err := w.flush(); err != nil {
+			c.exportFailed(err)
+			return
+		}
+		if len(results) < exportBatchSize {
+			break
+		}
+
+		// 记录最后一行的排序字段值，作为下一批的游标
+		row := results[len(results)-1]
+		last = make([]interface{}, len(keys))
+		for i, key := range keys {
+			last[i] = row[key.Field]
+			if b, ok := last[i].([]byte); ok {
+				last[i] = string(b)
+			}
+		}
+	}
+	if err := w.close(); err != nil {
+		c.exportFailed(err)
+		return
+	}
+
+	// 10. 执行后置钩子，数据已经发送给客户端，失败时只记录错误
+	if c.afterHook != nil {
+		if err := c.afterHook(c); err != nil {
+			_ = ctx.Error(fmt.Errorf("列表查询后置钩子执行失败: %w", err))
+		}
+	}
+}
+
+// exportFailed 处理导出过程中的错误
+// 还没有写入数据时返回错误响应，已经写入数据时响应无法修改，只能记录错误并中断请求
+func (c *Core[T]) exportFailed(err error) {
+	if !c.ginCtx.Writer.Written() {
+		c.ginCtx.Writer.Header().Del("Content-Type")
+		c.ginCtx.Writer.Header().Del("Content-Disposition")
+		c.err = cError.New(cError.ErrDBQuery, nil, err)
+		return
+	}
+	_ = c.ginCtx.Error(err)
+	c.ginCtx.Abort()
+}
+
+// newExportWriter 根据导出格式创建 exportWriter
+func newExportWriter(format string, w gin.ResponseWriter) exportWriter {
+	switch format {
+	case exportXLSX:
+		return newXLSXExportWriter(w)
+	case exportNDJSON:
+		return &ndjsonExportWriter{w: w}
+	}
+	return &csvExportWriter{w: w, csv: csv.NewWriter(w)}
+}
+
+// exportCell 将数据库中的值转换为表格中的单元格，时间使用 RFC3339 格式
+func exportCell(value interface{}) interface{} {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case []byte:
+		return string(v)
+	case time.Time:
+		return v.Format(time.RFC3339)
+	}
+	return value
+}
+
+// csvExportWriter 导出 csv 格式
+type csvExportWriter struct {
+	w   gin.ResponseWriter
+	csv *csv.Writer
+}
+
+func (e *csvExportWriter) writeHeader(columns []string) error {
+	// 写入 UTF-8 BOM，否则 Excel 打开时中文会乱码
+	if _, err := io.WriteString(e.w, "\uFEFF"); err != nil {
+		return err
+	}
+	return e.csv.Write(columns)
+}
+
+func (e *csvExportWriter) writeRow(values []interface{}) error {
+	record := make([]string, len(values))
+	for i, value := range values {
+		record[i] = cast.ToString(exportCell(value))
+	}
+	return e.csv.Write(record)
+}
+
+func (e *csvExportWriter) flush() error {
+	e.csv.Flush()
+	if err := e.csv.Error(); err != nil {
+		return err
+	}
+	e.w.Flush()
+	return nil
+}
+
+func (e *csvExportWriter) close() error {
+	return e.flush()
+}
+
+// ndjsonExportWriter 导出 ndjson 格式，每行一个 JSON 对象，没有表头
+type ndjsonExportWriter struct {
+	w       gin.ResponseWriter
+	columns []string
+}
+
+func (e *ndjsonExportWriter) writeHeader(columns []string) error {
+	e.columns = columns
+	return nil
+}
+
+func (e *ndjsonExportWriter) writeRow(values []interface{}) error {
+	row := make(map[string]interface{}, len(values))
+	for i, value := range values {
+		// 部分数据库驱动会将字符串扫描为 []byte，直接序列化会变成 base64
+		if b, ok := value.([]byte); ok {
+			value = string(b)
+		}
+		row[e.columns[i]] = value
+	}
+	data, err := json.Marshal(row)
+	if err != nil {
+		return err
+	}
+	_, err = e.w.Write(append(data, '\n'))
+	return err
+}
+
+func (e *ndjsonExportWriter) flush() error {
+	e.w.Flush()
+	return nil
+}
+
+func (e *ndjsonExportWriter) close() error {
+	return nil
+}
+
+// xlsxExportWriter 导出 xlsx 格式
+// 使用 excelize 的流式写入，超出内存缓冲的行会写入临时文件，所有数据写入之后再发送给客户端
+type xlsxExportWriter struct {
+	w      gin.ResponseWriter
+	file   *excelize.File
+	stream *excelize.StreamWriter
+	err    error
+	row    int
+}
+
+func newXLSXExportWriter(w gin.ResponseWriter) *xlsxExportWriter {
+	file := excelize.NewFile()
+	stream, err := file.NewStreamWriter(file.GetSheetName(0))
+	return &xlsxExportWriter{w: w, file: file, stream: stream, err: err}
+}
+
+func (e *xlsxExportWriter) writeHeader(columns []string) error {
+	values := make([]interface{}, len(columns))
+	for i, column := range columns {
+		values[i] = column
+	}
+	return e.writeRow(values)
+}
+
+func (e *xlsxExportWriter) writeRow(values []interface{}) error {
+	if e.err != nil {
+		return e.err
+	}
+	cells := make([]interface{}, len(values))
+	for i, value := range values {
+		cells[i] = exportCell(value)
+	}
+	e.row++
+	cell, err := excelize.CoordinatesToCellName(1, e.row)
+	if err != nil {
+		return err
+	}
+	return e.stream.SetRow(cell, cells)
+}
+
+func (e *xlsxExportWriter) flush() error {
+	return nil
+}
+
+func (e *xlsxExportWriter) close() error {
+	defer e.file.Close()
+	if err := e.stream.Flush(); err != nil {
+		return err
+	}
+	_, err := e.file.WriteTo(e.w)
+	return err
+}
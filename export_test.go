@@ -0,0 +1,188 @@
+package crud
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/polaris0915/go-crud/model"
+	"github.com/xuri/excelize/v2"
+)
+
+func TestExport(t *testing.T) {
+	r, db := newTestServer(t, &model.RelateType{}, &model.File{})
+	queries := countQueries(t, db)
+
+	// 20 个图片文件，大小有重复，用于检查分批读取时相同排序值的记录不会重复或者遗漏
+	var want [][]string
+	for i := 1; i <= 23; i++ {
+		fileType := "image"
+		if i > 20 {
+			fileType = "doc"
+		}
+		size := uint64(i%7) * 100
+		db.Create(&model.File{
+			ID:       uint64(i),
+			FileName: fmt.Sprintf("文件%d", i),
+			FilePath: fmt.Sprintf("/storage/file%d", i),
+			FileType: fileType,
+			FileSize: size,
+		})
+	}
+	for size := 6; size >= 0; size-- {
+		for i := 1; i <= 20; i++ {
+			if i%7 == size {
+				want = append(want, []string{fmt.Sprintf("文件%d", i), fmt.Sprint(size * 100)})
+			}
+		}
+	}
+
+	defer func(size int) { exportBatchSize = size }(exportBatchSize)
+	exportBatchSize = 5
+
+	RegisterModelApi[*model.File](r.Group("/api"), "file")
+	do := func(url, accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, url, nil)
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("csv", func(t *testing.T) {
+		*queries = 0
+		w := do("/api/file/export?fields=file_name,file_size&sort=-file_size&file_type=image", "")
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d, body = %s", w.Code, w.Body.String())
+		}
+		if ct := w.Header().Get("Content-Type"); ct != "text/csv" {
+			t.Errorf("Content-Type = %s", ct)
+		}
+		records, err := csv.NewReader(strings.NewReader(strings.TrimPrefix(w.Body.String(), "\uFEFF"))).ReadAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(records[0], []string{"file_name", "file_size"}) {
+			t.Errorf("header = %v", records[0])
+		}
+		if !reflect.DeepEqual(records[1:], want) {
+			t.Errorf("rows = %v, want %v", records[1:], want)
+		}
+		// 20 条记录每批 5 条，最后一次查询没有结果
+		if *queries != 5 {
+			t.Errorf("queries = %d, want 5", *queries)
+		}
+	})
+
+	t.Run("ndjson", func(t *testing.T) {
+		w := do("/api/file/export?fields=file_name&file_type=doc", "application/x-ndjson")
+		if ct := w.Header().Get("Content-Type"); ct != "application/x-ndjson" {
+			t.Fatalf("Content-Type = %s, body = %s", ct, w.Body.String())
+		}
+		var names []string
+		scanner := bufio.NewScanner(w.Body)
+		for scanner.Scan() {
+			var row map[string]interface{}
+			if err := json.Unmarshal(scanner.Bytes(), &row); err != nil {
+				t.Fatal(err)
+			}
+			names = append(names, row["file_name"].(string))
+		}
+		if !reflect.DeepEqual(names, []string{"文件21", "文件22", "文件23"}) {
+			t.Errorf("names = %v", names)
+		}
+	})
+
+	t.Run("xlsx", func(t *testing.T) {
+		w := do("/api/file/export?format=xlsx&fields=file_name,file_type&filter=file_size>=600", "")
+		f, err := excelize.OpenReader(bytes.NewReader(w.Body.Bytes()))
+		if err != nil {
+			t.Fatalf("open xlsx: %v, body = %s", err, w.Body.String())
+		}
+		defer f.Close()
+		rows, err := f.GetRows(f.GetSheetName(0))
+		if err != nil {
+			t.Fatal(err)
+		}
+		wantRows := [][]string{{"file_name", "file_type"}, {"文件6", "image"}, {"文件13", "image"}, {"文件20", "image"}}
+		if !reflect.DeepEqual(rows, wantRows) {
+			t.Errorf("rows = %v, want %v", rows, wantRows)
+		}
+	})
+
+	for _, url := range []string{
+		"/api/file/export?format=pdf",
+		"/api/file/export?fields=deleted_at",
+		"/api/file/export?sort=deleted_at",
+	} {
+		if w := do(url, ""); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, body = %s", url, w.Code, w.Body.String())
+		}
+	}
+}
+
+type exportTask struct {
+	ID       uint64     `gorm:"column:id;primary_key" json:"id" crud:"allow_get"`
+	Priority int        `gorm:"column:priority" json:"priority" crud:"allow_get"`
+	DueAt    *time.Time `gorm:"column:due_at" json:"due_at" crud:"allow_get"`
+}
+
+func (e *exportTask) TableName() string { return "export_task" }
+
+func TestExportBatches(t *testing.T) {
+	r, db := newTestServer(t, &exportTask{})
+
+	// 一半记录的 due_at 为空，priority 有重复值
+	dueAt := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 1; i <= 3*exportBatchSize+1; i++ {
+		task := exportTask{ID: uint64(i), Priority: i % 3}
+		if i%2 == 0 {
+			task.DueAt = &dueAt
+		}
+		db.Create(&task)
+	}
+
+	RegisterModelApi[*exportTask](r.Group("/api"), "task")
+	do := func(url string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, url, nil))
+		return w
+	}
+
+	w := do("/api/task/export?format=ndjson&fields=id&sort=-priority")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", w.Code, w.Body.String())
+	}
+	seen := make(map[uint64]bool)
+	scanner := bufio.NewScanner(w.Body)
+	for scanner.Scan() {
+		var row struct {
+			ID uint64 `json:"id"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &row); err != nil {
+			t.Fatal(err)
+		}
+		if seen[row.ID] {
+			t.Errorf("id %d exported twice", row.ID)
+		}
+		seen[row.ID] = true
+	}
+	if len(seen) != 3*exportBatchSize+1 {
+		t.Errorf("exported %d rows, want %d", len(seen), 3*exportBatchSize+1)
+	}
+
+	// 按可以为空的字段分批读取时，边界行为 NULL 会导致导出提前结束，直接拒绝
+	if w := do("/api/task/export?sort=due_at"); w.Code != http.StatusBadRequest {
+		t.Errorf("nullable sort: status = %d, body = %s", w.Code, w.Body.String())
+	}
+}
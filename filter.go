@@ -0,0 +1,223 @@
+package crud
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/spf13/cast"
+	"gorm.io/gorm"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// 过滤操作符
+const (
+	opEq      = "eq"      // 等于（默认）
+	opNe      = "ne"      // 不等于
+	opGt      = "gt"      // 大于
+	opGte     = "gte"     // 大于等于
+	opLt      = "lt"      // 小于
+	opLte     = "lte"     // 小于等于
+	opLike    = "like"    // 包含
+	opILike   = "ilike"   // 包含（忽略大小写）
+	opStart   = "start"   // 开头匹配
+	opEnd     = "end"     // 结尾匹配
+	opBetween = "between" // 区间 between:a,b
+	opIn      = "in"      // 集合 in:a,b,c
+	opNin     = "nin"     // 不在集合中 nin:a,b,c
+	opIs      = "is"      // 空值判断 is:null / is:notnull
+)
+
+// filterOperators 所有支持的过滤操作符
+var filterOperators = map[string]struct{}{
+	opEq: empty, opNe: empty, opGt: empty, opGte: empty, opLt: empty, opLte: empty,
+	opLike: empty, opILike: empty, opStart: empty, opEnd: empty,
+	opBetween: empty, opIn: empty, opNin: empty, opIs: empty,
+}
+
+var (
+	timeType      = reflect.TypeOf(time.Time{})
+	deletedAtType = reflect.TypeOf(gorm.DeletedAt{})
+	nullTimeType  = reflect.TypeOf(sql.NullTime{})
+)
+
+// filterCondition 单个字段的过滤条件
+type filterCondition struct {
+	Field    string
+	Operator string
+	Values   []interface{}
+}
+
+// parseQueryCondition 解析查询参数中的过滤条件
+// 例如 status=in:1,2 会被解析为 {Field: status, Operator: in, Values: [1, 2]}
+// 没有可识别前缀的值按照精确匹配处理
+func parseQueryCondition(field, raw string) filterCondition {
+	cond := filterCondition{Field: field, Operator: opEq, Values: []interface{}{raw}}
+
+	prefix, value, ok := strings.Cut(raw, ":")
+	if !ok {
+		return cond
+	}
+	if _, known := filterOperators[prefix]; !known {
+		return cond
+	}
+
+	cond.Operator = prefix
+	switch prefix {
+	case opBetween, opIn, opNin:
+		cond.Values = cond.Values[:0]
+		for _, v := range strings.Split(value, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				cond.Values = append(cond.Values, v)
+			}
+		}
+	default:
+		cond.Values = []interface{}{value}
+	}
+	return cond
+}
+
+// buildCondition 将过滤条件编译为参数化的查询语句
+// 字段必须是allow_get的字段，值会根据字段的Go类型进行转换校验
+func (r *RegisteredModel) buildCondition(cond filterCondition) (query string, args []interface{}, err error) {
+	if _, ok := r.AllowGetFields[cond.Field]; !ok {
+		return "", nil, fmt.Errorf("不允许按字段 %s 过滤", cond.Field)
+	}
+	field := r.fieldByJsonTag(cond.Field)
+	if field == nil {
+		return "", nil, fmt.Errorf("字段 %s 不存在", cond.Field)
+	}
+	return buildColumnCondition(cond, cond.Field, filterFieldType(field.Type))
+}
+
+// buildColumnCondition 根据值的类型将过滤条件编译为参数化的查询语句
+// column 为列名或者SQL表达式，cond.Field 只用于错误信息
+func buildColumnCondition(cond filterCondition, column string, fieldType reflect.Type) (query string, args []interface{}, err error) {
+	switch cond.Operator {
+	case opIs:
+		if len(cond.Values) != 1 {
+			return "", nil, fmt.Errorf("字段 %s 的 is 操作符只接受一个值", cond.Field)
+		}
+		switch strings.ToLower(cast.ToString(cond.Values[0])) {
+		case "null":
+			return fmt.Sprintf("%s IS NULL", column), nil, nil
+		case "notnull":
+			return fmt.Sprintf("%s IS NOT NULL", column), nil, nil
+		}
+		return "", nil, fmt.Errorf("字段 %s 的 is 操作符只支持 null 或 notnull", cond.Field)
+
+	case opLike, opILike, opStart, opEnd:
+		if fieldType.Kind() != reflect.String {
+			return "", nil, fmt.Errorf("字段 %s 不是字符串类型，不支持 %s 操作符", cond.Field, cond.Operator)
+		}
+		if len(cond.Values) != 1 {
+			return "", nil, fmt.Errorf("字段 %s 的 %s 操作符只接受一个值", cond.Field, cond.Operator)
+		}
+		value := cast.ToString(cond.Values[0])
+		switch cond.Operator {
+		case opLike:
+			return fmt.Sprintf("%s LIKE ?", column), []interface{}{fmt.Sprintf("%%%s%%", value)}, nil
+		case opILike:
+			return fmt.Sprintf("LOWER(%s) LIKE ?", column), []interface{}{fmt.Sprintf("%%%s%%", strings.ToLower(value))}, nil
+		case opStart:
+			return fmt.Sprintf("%s LIKE ?", column), []interface{}{fmt.Sprintf("%s%%", value)}, nil
+		default:
+			return fmt.Sprintf("%s LIKE ?", column), []interface{}{fmt.Sprintf("%%%s", value)}, nil
+		}
+	}
+
+	// 剩下的操作符都需要将值转换为字段类型
+	values := make([]interface{}, 0, len(cond.Values))
+	for _, raw := range cond.Values {
+		value, err := convertFilterValue(fieldType, raw)
+		if err != nil {
+			return "", nil, fmt.Errorf("字段 %s 的值 %v 无效: %w", cond.Field, raw, err)
+		}
+		values = append(values, value)
+	}
+
+	switch cond.Operator {
+	case opEq, opNe, opGt, opGte, opLt, opLte:
+		if len(values) != 1 {
+			return "", nil, fmt.Errorf("字段 %s 的 %s 操作符只接受一个值", cond.Field, cond.Operator)
+		}
+		if cond.Operator != opEq && cond.Operator != opNe && !isOrderedType(fieldType) {
+			return "", nil, fmt.Errorf("字段 %s 不是数值或时间类型，不支持 %s 操作符", cond.Field, cond.Operator)
+		}
+		return fmt.Sprintf("%s %s ?", column, comparisonSymbols[cond.Operator]), values, nil
+
+	case opBetween:
+		if len(values) != 2 {
+			return "", nil, fmt.Errorf("字段 %s 的 between 操作符需要两个值", cond.Field)
+		}
+		if !isOrderedType(fieldType) {
+			return "", nil, fmt.Errorf("字段 %s 不是数值或时间类型，不支持 between 操作符", cond.Field)
+		}
+		return fmt.Sprintf("%s BETWEEN ? AND ?", column), values, nil
+
+	case opIn, opNin:
+		if len(values) == 0 {
+			return "", nil, fmt.Errorf("字段 %s 的 %s 操作符至少需要一个值", cond.Field, cond.Operator)
+		}
+		if cond.Operator == opIn {
+			return fmt.Sprintf("%s IN ?", column), []interface{}{values}, nil
+		}
+		return fmt.Sprintf("%s NOT IN ?", column), []interface{}{values}, nil
+	}
+
+	return "", nil, fmt.Errorf("不支持的过滤操作符 %s", cond.Operator)
+}
+
+// comparisonSymbols 比较操作符对应的SQL符号
+var comparisonSymbols = map[string]string{
+	opEq: "=", opNe: "<>", opGt: ">", opGte: ">=", opLt: "<", opLte: "<=",
+}
+
+// filterFieldType 获取字段用于过滤时的实际类型，指针类型取其元素类型
+func filterFieldType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
+
+// isTimeType 判断字段是否是时间类型
+func isTimeType(t reflect.Type) bool {
+	return t == timeType || t == deletedAtType || t == nullTimeType
+}
+
+// isNumericKind 判断字段是否是数值类型
+func isNumericKind(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// isOrderedType 判断字段是否支持大小比较
+func isOrderedType(t reflect.Type) bool {
+	return isTimeType(t) || isNumericKind(t.Kind())
+}
+
+// convertFilterValue 将过滤值转换为字段对应的Go类型
+func convertFilterValue(t reflect.Type, raw interface{}) (interface{}, error) {
+	if isTimeType(t) {
+		return cast.ToTimeE(raw)
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return cast.ToStringE(raw)
+	case reflect.Bool:
+		return cast.ToBoolE(raw)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return cast.ToInt64E(raw)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return cast.ToUint64E(raw)
+	case reflect.Float32, reflect.Float64:
+		return cast.ToFloat64E(raw)
+	}
+	return nil, fmt.Errorf("不支持过滤的字段类型 %s", t)
+}
@@ -0,0 +1,358 @@
+package crud
+
+import (
+	"fmt"
+	"strings"
+)
+
+// 过滤表达式的逻辑关系
+const (
+	logicAnd = "and"
+	logicOr  = "or"
+	logicNot = "not"
+)
+
+// filterExpr 过滤表达式语法树
+// 叶子节点只有 Cond，分支节点根据 Logic 组合 Children
+type filterExpr struct {
+	Logic    string
+	Children []*filterExpr
+	Cond     *filterCondition
+}
+
+// filterExprError 过滤表达式解析错误，Pos 为出错位置（从0开始）
+type filterExprError struct {
+	Pos int
+	Msg string
+}
+
+func (e *filterExprError) Error() string {
+	return fmt.Sprintf("过滤表达式第%d个字符处错误: %s", e.Pos, e.Msg)
+}
+
+// Detail 用于错误响应中的详情信息
+func (e *filterExprError) Detail() map[string]interface{} {
+	return map[string]interface{}{
+		"position": e.Pos,
+		"message":  e.Msg,
+	}
+}
+
+// filterExprOperators 表达式中 =xxx= 形式的操作符与过滤操作符的对应关系
+// 除了这里的别名，所有 filterOperators 中的操作符都可以直接使用，例如 =like=
+var filterExprOperators = map[string]string{
+	"ge":  opGte,
+	"le":  opLte,
+	"out": opNin,
+}
+
+// parseFilterExpr 解析 RSQL/FIQL 风格的过滤表达式
+//
+//	; 或 and   AND，优先级高于 OR
+//	, 或 or    OR
+//	( )        分组
+//	==  !=     等于 不等于
+//	>  >=  <  <=  或 =gt= =ge= =lt= =le=
+//	=in=(a,b) =out=(a,b) =between=(a,b) =like= =ilike= =start= =end= =is=null
+//
+// 例如 (status==open,assignee==42);created_at>=2026-01-01
+func parseFilterExpr(input string) (*filterExpr, error) {
+	p := &filterExprParser{input: input}
+	p.skipSpace()
+	if p.eof() {
+		return nil, &filterExprError{Pos: 0, Msg: "过滤表达式不能为空"}
+	}
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	if !p.eof() {
+		return nil, p.errorf("无法识别的字符 %q", p.input[p.pos])
+	}
+	return expr, nil
+}
+
+type filterExprParser struct {
+	input string
+	pos   int
+}
+
+func (p *filterExprParser) eof() bool {
+	return p.pos >= len(p.input)
+}
+
+func (p *filterExprParser) peek() byte {
+	if p.eof() {
+		return 0
+	}
+	return p.input[p.pos]
+}
+
+func (p *filterExprParser) skipSpace() {
+	for !p.eof() && (p.input[p.pos] == ' ' || p.input[p.pos] == '\t') {
+		p.pos++
+	}
+}
+
+// matchKeyword 匹配前后都是空白（或者括号）的 and/or 关键字，匹配成功时跳过该关键字
+func (p *filterExprParser) matchKeyword(keyword string) bool {
+	end := p.pos + len(keyword)
+	if p.pos == 0 || p.input[p.pos-1] != ' ' && p.input[p.pos-1] != '\t' || end >= len(p.input) {
+		return false
+	}
+	if !strings.EqualFold(p.input[p.pos:end], keyword) {
+		return false
+	}
+	if next := p.input[end]; next != ' ' && next != '\t' && next != '(' {
+		return false
+	}
+	p.pos = end
+	return true
+}
+
+func (p *filterExprParser) errorf(format string, args ...interface{}) error {
+	return &filterExprError{Pos: p.pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+// parseOr or := and (',' and)*
+func (p *filterExprParser) parseOr() (*filterExpr, error) {
+	first, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	children := []*filterExpr{first}
+	for p.skipSpace(); p.peek() == ',' || p.matchKeyword(logicOr); p.skipSpace() {
+		if p.peek() == ',' {
+			p.pos++
+		}
+		next, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, next)
+	}
+	if len(children) == 1 {
+		return first, nil
+	}
+	return &filterExpr{Logic: logicOr, Children: children}, nil
+}
+
+// parseAnd and := constraint (';' constraint)*
+func (p *filterExprParser) parseAnd() (*filterExpr, error) {
+	first, err := p.parseConstraint()
+	if err != nil {
+		return nil, err
+	}
+	children := []*filterExpr{first}
+	for p.skipSpace(); p.peek() == ';' || p.matchKeyword(logicAnd); p.skipSpace() {
+		if p.peek() == ';' {
+			p.pos++
+		}
+		next, err := p.parseConstraint()
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, next)
+	}
+	if len(children) == 1 {
+		return first, nil
+	}
+	return &filterExpr{Logic: logicAnd, Children: children}, nil
+}
+
+// parseConstraint constraint := '(' or ')' | comparison
+func (p *filterExprParser) parseConstraint() (*filterExpr, error) {
+	p.skipSpace()
+	if p.peek() != '(' {
+		return p.parseComparison()
+	}
+	p.pos++
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	if p.peek() != ')' {
+		return nil, p.errorf("缺少右括号")
+	}
+	p.pos++
+	return expr, nil
+}
+
+// parseComparison comparison := selector operator arguments
+func (p *filterExprParser) parseComparison() (*filterExpr, error) {
+	field := p.parseSelector()
+	if field == "" {
+		return nil, p.errorf("缺少字段名")
+	}
+	p.skipSpace()
+	operator, err := p.parseOperator()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+
+	cond := &filterCondition{Field: field, Operator: operator}
+	if p.peek() == '(' {
+		p.pos++
+		for {
+			p.skipSpace()
+			value, err := p.parseValue()
+			if err != nil {
+				return nil, err
+			}
+			cond.Values = append(cond.Values, value)
+			p.skipSpace()
+			if p.peek() == ',' {
+				p.pos++
+				continue
+			}
+			if p.peek() != ')' {
+				return nil, p.errorf("值列表缺少右括号")
+			}
+			p.pos++
+			break
+		}
+	} else {
+		value, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		cond.Values = []interface{}{value}
+	}
+	return &filterExpr{Cond: cond}, nil
+}
+
+func (p *filterExprParser) parseSelector() string {
+	start := p.pos
+	for !p.eof() {
+		ch := p.input[p.pos]
+		if ch == '_' || ch == '.' || ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z' || p.pos > start && ch >= '0' && ch <= '9' {
+			p.pos++
+			continue
+		}
+		break
+	}
+	return p.input[start:p.pos]
+}
+
+func (p *filterExprParser) parseOperator() (string, error) {
+	start := p.pos
+	rest := p.input[p.pos:]
+	switch {
+	case strings.HasPrefix(rest, "=="):
+		p.pos += 2
+		return opEq, nil
+	case strings.HasPrefix(rest, "!="):
+		p.pos += 2
+		return opNe, nil
+	case strings.HasPrefix(rest, ">="):
+		p.pos += 2
+		return opGte, nil
+	case strings.HasPrefix(rest, "<="):
+		p.pos += 2
+		return opLte, nil
+	case strings.HasPrefix(rest, ">"):
+		p.pos++
+		return opGt, nil
+	case strings.HasPrefix(rest, "<"):
+		p.pos++
+		return opLt, nil
+	case strings.HasPrefix(rest, "="):
+		end := strings.IndexByte(rest[1:], '=')
+		if end <= 0 {
+			return "", p.errorf("无效的操作符")
+		}
+		name := strings.ToLower(rest[1 : end+1])
+		if alias, ok := filterExprOperators[name]; ok {
+			name = alias
+		}
+		if _, ok := filterOperators[name]; !ok {
+			return "", &filterExprError{Pos: start, Msg: fmt.Sprintf("不支持的操作符 =%s=", rest[1:end+1])}
+		}
+		p.pos += end + 2
+		return name, nil
+	}
+	return "", p.errorf("缺少操作符")
+}
+
+// parseValue 解析单个值，支持单引号或双引号包裹，引号内可以使用反斜杠转义
+func (p *filterExprParser) parseValue() (string, error) {
+	if p.eof() {
+		return "", p.errorf("缺少值")
+	}
+
+	quote := p.peek()
+	if quote == '\'' || quote == '"' {
+		start := p.pos
+		p.pos++
+		var sb strings.Builder
+		for !p.eof() {
+			ch := p.input[p.pos]
+			if ch == '\\' && p.pos+1 < len(p.input) {
+				sb.WriteByte(p.input[p.pos+1])
+				p.pos += 2
+				continue
+			}
+			if ch == quote {
+				p.pos++
+				return sb.String(), nil
+			}
+			sb.WriteByte(ch)
+			p.pos++
+		}
+		return "", &filterExprError{Pos: start, Msg: "引号没有闭合"}
+	}
+
+	start := p.pos
+	for !p.eof() && !strings.ContainsRune("'\"();,=!<> \t", rune(p.input[p.pos])) {
+		p.pos++
+	}
+	if start == p.pos {
+		return "", p.errorf("缺少值")
+	}
+	return p.input[start:p.pos], nil
+}
+
+// buildFilterExpr 将过滤表达式编译为参数化的查询语句
+// 每个叶子节点都通过 buildCondition 检查字段是否allow_get以及值的类型
+func (r *RegisteredModel) buildFilterExpr(expr *filterExpr) (query string, args []interface{}, err error) {
+	return buildExpr(expr, r.buildCondition)
+}
+
+// buildExpr 将表达式编译为参数化的查询语句，叶子节点由 leaf 编译
+func buildExpr(
+	expr *filterExpr, leaf func(filterCondition) (string, []interface{}, error),
+) (query string, args []interface{}, err error) {
+	if expr.Cond != nil {
+		return leaf(*expr.Cond)
+	}
+
+	if len(expr.Children) == 0 {
+		return "", nil, fmt.Errorf("过滤条件分组 %s 不能为空", expr.Logic)
+	}
+
+	parts := make([]string, 0, len(expr.Children))
+	for _, child := range expr.Children {
+		childQuery, childArgs, err := buildExpr(child, leaf)
+		if err != nil {
+			return "", nil, err
+		}
+		parts = append(parts, "("+childQuery+")")
+		args = append(args, childArgs...)
+	}
+
+	switch expr.Logic {
+	case logicAnd:
+		return strings.Join(parts, " AND "), args, nil
+	case logicOr:
+		return strings.Join(parts, " OR "), args, nil
+	case logicNot:
+		if len(parts) != 1 {
+			return "", nil, fmt.Errorf("not 只能包含一个过滤条件")
+		}
+		return "NOT " + parts[0], args, nil
+	}
+	return "", nil, fmt.Errorf("不支持的逻辑关系 %s", expr.Logic)
+}
@@ -0,0 +1,126 @@
+package crud
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/polaris0915/go-crud/cError"
+)
+
+func TestBuildFilterExpr(t *testing.T) {
+	meta := newFilterTestMeta()
+
+	tests := []struct {
+		input string
+		query string
+		args  int
+	}{
+		{"status==open", "status = ?", 1},
+		{"(status==open,id==42);created_at>=2026-01-01", "((status = ?) OR (id = ?)) AND (created_at >= ?)", 3},
+		{"status==open;id=gt=1,score<2", "((status = ?) AND (id > ?)) OR (score < ?)", 3},
+		{"status=in=(open,'in progress')", "status IN ?", 1},
+		{"score=between=(1, 2.5)", "score BETWEEN ? AND ?", 2},
+		{"status=ilike='O\\'Neil'", "LOWER(status) LIKE ?", 1},
+		{"closed_at=is=null", "closed_at IS NULL", 0},
+		{"id=out=(1,2)", "id NOT IN ?", 1},
+		{"(status==open or id==42) and created_at>=2026-01-01", "((status = ?) OR (id = ?)) AND (created_at >= ?)", 3},
+	}
+	for _, tt := range tests {
+		expr, err := parseFilterExpr(tt.input)
+		if err != nil {
+			t.Errorf("%s: unexpected parse error: %v", tt.input, err)
+			continue
+		}
+		query, args, err := meta.buildFilterExpr(expr)
+		if err != nil {
+			t.Errorf("%s: unexpected build error: %v", tt.input, err)
+			continue
+		}
+		if query != tt.query || len(args) != tt.args {
+			t.Errorf("%s: got %q with %d args, want %q with %d args", tt.input, query, len(args), tt.query, tt.args)
+		}
+	}
+}
+
+func TestParseFilterExprErrorPosition(t *testing.T) {
+	tests := []struct {
+		input string
+		pos   int
+	}{
+		{"", 0},
+		{"status", 6},
+		{"status==open;", 13},
+		{"(status==open", 13},
+		{"status=foo=1", 6},
+		{"status=='open", 8},
+		{"status==open)", 12},
+	}
+	for _, tt := range tests {
+		_, err := parseFilterExpr(tt.input)
+		var exprErr *filterExprError
+		if !errors.As(err, &exprErr) {
+			t.Errorf("%q: expected filterExprError, got %v", tt.input, err)
+			continue
+		}
+		if exprErr.Pos != tt.pos {
+			t.Errorf("%q: got position %d, want %d (%s)", tt.input, exprErr.Pos, tt.pos, exprErr.Msg)
+		}
+	}
+}
+
+func TestBuildFilterExprRejectsFields(t *testing.T) {
+	meta := newFilterTestMeta()
+
+	for _, input := range []string{"secret==x", "status==open,secret==x", "id>abc"} {
+		expr, err := parseFilterExpr(input)
+		if err != nil {
+			t.Fatalf("%s: unexpected parse error: %v", input, err)
+		}
+		if _, _, err := meta.buildFilterExpr(expr); err == nil {
+			t.Errorf("%s: expected build error", input)
+		}
+	}
+}
+
+func TestParseFilterParamsRawQuery(t *testing.T) {
+	meta := newFilterTestMeta()
+
+	tests := []struct {
+		query string
+		want  string
+		code  int
+	}{
+		// net/url 会丢弃包含未编码 ; 的参数，filter 必须仍然生效
+		{"filter=(status==open,id==42);created_at>=2026-01-01", "((status = ?) OR (id = ?)) AND (created_at >= ?)", 0},
+		{"filter=status%3D%3Dopen%3Bid%3D%3D1&page=2", "(status = ?) AND (id = ?)", 0},
+		{"page=1", "", 0},
+		{"status=open;closed", "", cError.ErrReadFilter},
+		{"filter=status==%ZZ", "", cError.ErrReadFilter},
+	}
+	for _, tt := range tests {
+		ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+		ctx.Request = httptest.NewRequest(http.MethodGet, "/api/x?"+tt.query, nil)
+		c := &Core[*filterTestModel]{ginCtx: ctx}
+		_, where := c.parseFilterParams(meta, listQueryParams)
+		if tt.code != 0 {
+			if c.err == nil || c.err.Code != tt.code {
+				t.Errorf("%s: err = %v, want code %d", tt.query, c.err, tt.code)
+			}
+			continue
+		}
+		if c.err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.query, c.err)
+			continue
+		}
+		var got string
+		if where != nil {
+			got, _, _ = meta.buildFilterExpr(where)
+		}
+		if got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
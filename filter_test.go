@@ -0,0 +1,88 @@
+package crud
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+type filterTestModel struct {
+	ID        uint64     `json:"id" crud:"allow_get"`
+	Status    string     `json:"status" crud:"allow_get"`
+	Score     float64    `json:"score" crud:"allow_get"`
+	Enabled   bool       `json:"enabled" crud:"allow_get"`
+	CreatedAt time.Time  `json:"created_at" crud:"allow_get"`
+	ClosedAt  *time.Time `json:"closed_at" crud:"allow_get"`
+	Secret    string     `json:"secret"`
+}
+
+func (m *filterTestModel) TableName() string {
+	return "filter_test_model"
+}
+
+func newFilterTestMeta() *RegisteredModel {
+	r := &RegisteredModel{
+		ModelName:             "filter_test_model",
+		Rules:                 make(map[string]map[string]interface{}),
+		RequireOnCreateFields: make(map[string]struct{}),
+		PartialUpdateFields:   make(map[string]struct{}),
+		AllowGetFields:        make(map[string]struct{}),
+	}
+	deepResolve(r, reflect.TypeOf(filterTestModel{}))
+	return r
+}
+
+func TestBuildCondition(t *testing.T) {
+	meta := newFilterTestMeta()
+
+	tests := []struct {
+		field, raw string
+		query      string
+		args       int
+	}{
+		{"status", "open", "status = ?", 1},
+		{"status", "ne:open", "status <> ?", 1},
+		{"status", "ilike:OPEN", "LOWER(status) LIKE ?", 1},
+		{"status", "in:open,closed", "status IN ?", 1},
+		{"status", "nin:open", "status NOT IN ?", 1},
+		{"id", "gte:10", "id >= ?", 1},
+		{"score", "between:1.5,3", "score BETWEEN ? AND ?", 2},
+		{"created_at", "lt:2026-01-01", "created_at < ?", 1},
+		{"closed_at", "is:null", "closed_at IS NULL", 0},
+		{"closed_at", "is:notnull", "closed_at IS NOT NULL", 0},
+		{"enabled", "true", "enabled = ?", 1},
+		{"status", "unknown:x", "status = ?", 1},
+	}
+	for _, tt := range tests {
+		query, args, err := meta.buildCondition(parseQueryCondition(tt.field, tt.raw))
+		if err != nil {
+			t.Errorf("%s=%s: unexpected error: %v", tt.field, tt.raw, err)
+			continue
+		}
+		if query != tt.query || len(args) != tt.args {
+			t.Errorf("%s=%s: got %q with %d args, want %q with %d args", tt.field, tt.raw, query, len(args), tt.query, tt.args)
+		}
+	}
+}
+
+func TestBuildConditionRejectsInvalidValues(t *testing.T) {
+	meta := newFilterTestMeta()
+
+	tests := []struct{ field, raw string }{
+		{"id", "abc"},
+		{"id", "gt:-1"},
+		{"status", "gt:open"},
+		{"enabled", "between:true,false"},
+		{"score", "like:1"},
+		{"score", "between:1"},
+		{"created_at", "gte:not-a-date"},
+		{"closed_at", "is:empty"},
+		{"status", "in:"},
+		{"secret", "x"},
+	}
+	for _, tt := range tests {
+		if _, _, err := meta.buildCondition(parseQueryCondition(tt.field, tt.raw)); err == nil {
+			t.Errorf("%s=%s: expected error", tt.field, tt.raw)
+		}
+	}
+}
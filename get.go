@@ -3,12 +3,12 @@ package crud
 import (
 	"errors"
 	"fmt"
-	"github.com/iancoleman/strcase"
 	"github.com/polaris0915/go-crud/cError"
 	"github.com/polaris0915/go-crud/model"
-	"github.com/spf13/cast"
 	"net/http"
+	"slices"
 	"strings"
+	"time"
 )
 
 // TODO 需要添加添加获取字段信息的接口给用户
@@ -16,33 +16,25 @@ import (
 func (c *Core[T]) Get() {
 	ctx := c.ginCtx
 
+	// 嵌套路由检查父资源是否存在
+	if c.resolveParent(); c.err != nil {
+		return
+	}
+
 	// 解析请求参数
-	id := cast.ToUint64(ctx.Param("id"))
+	id := c.resourceID()
 	if id == 0 {
 		c.err = cError.New(cError.ErrReadInvalidID, nil, errors.New("资源ID不能为空"))
 		return
 	}
 
-	// 获取查询参数
-	fields := ctx.Query("fields")
-	expand := ctx.Query("expand")
-
-	// 解析字段选择参数
-	var requestedFields []string
-	if fields != "" {
-		requestedFields = strings.Split(fields, ",")
-		for i := range requestedFields {
-			requestedFields[i] = strings.TrimSpace(requestedFields[i])
-		}
+	// 解析字段选择、关联数据展开以及软删除记录的查询参数
+	requestedFields, expandRelations := c.readParams()
+	if c.err != nil {
+		return
 	}
-
-	// 解析关联数据展开参数
-	var expandRelations []string
-	if expand != "" {
-		expandRelations = strings.Split(expand, ",")
-		for i := range expandRelations {
-			expandRelations[i] = strings.TrimSpace(expandRelations[i])
-		}
+	if c.deleted = c.deletedQuery(); c.err != nil {
+		return
 	}
 
 	// 获取模型元数据
@@ -61,7 +53,81 @@ func (c *Core[T]) Get() {
 		}
 	}
 
-	// 检查读取字段的合法性
+	// 命中响应缓存时直接返回缓存的结果
+	cacheKey := c.responseCacheKey()
+	if c.replayCache(cacheKey) {
+		return
+	}
+
+	// 查询记录以及关联数据
+	result, extraFields := c.readOne(modelMeta, id, requestedFields, expandRelations)
+	if c.err != nil {
+		return
+	}
+
+	// 执行后置钩子
+	if c.afterHook != nil {
+		// TODO
+		if err := c.afterHook(c); err != nil {
+			c.err = cError.New(cError.ErrReadHookFailure, "查询后置钩子执行失败", err)
+			return
+		}
+	}
+
+	// ETag 为记录当前的版本，展开关联数据时关联数据的变化不会改变版本，使用返回的数据计算 ETag，此时的 ETag 不能用于 If-Match
+	// Last-Modified 为 updated_at 的值
+	var etag string
+	var modified time.Time
+	if field := modelMeta.updatedAtField(); field != nil {
+		modified = lastModified(result[field.GormFieldName])
+	}
+	if modelMeta.Version != nil && len(expandRelations) == 0 {
+		etag = versionETag(result[modelMeta.Version.GormFieldName])
+	}
+	for _, key := range extraFields {
+		delete(result, key)
+	}
+	if etag == "" {
+		contentTag, err := contentETag(result)
+		if err != nil {
+			c.err = cError.New(cError.ErrReadGeneral, nil, err)
+			return
+		}
+		etag = contentTag
+	}
+	c.storeCache(cacheKey, result, etag, modified)
+
+	// 客户端的缓存仍然有效时返回 304
+	if notModified(ctx, etag, modified) {
+		return
+	}
+
+	// 返回成功结果
+	HandleRes(ctx, http.StatusOK, result, "")
+}
+
+// readParams 解析 fields 以及 expand 参数
+func (c *Core[T]) readParams() ([]string, []*expandNode) {
+	// 解析字段选择参数
+	var requestedFields []string
+	if fields := c.ginCtx.Query("fields"); fields != "" {
+		requestedFields = strings.Split(fields, ",")
+		for i := range requestedFields {
+			requestedFields[i] = strings.TrimSpace(requestedFields[i])
+		}
+	}
+
+	// 解析关联数据展开参数
+	expandRelations, err := parseExpand(c.ginCtx.Query("expand"))
+	if err != nil {
+		c.err = cError.New(cError.ErrReadExpand, err.Error(), err)
+		return nil, nil
+	}
+	return requestedFields, expandRelations
+}
+
+// checkReadParams 检查读取的字段都是 allow_get 的字段，以及关联数据展开的合法性
+func (c *Core[T]) checkReadParams(modelMeta *RegisteredModel, requestedFields []string, expandRelations []*expandNode) {
 	for _, field := range requestedFields {
 		_, ok := modelMeta.AllowGetFields[field]
 		if !ok {
@@ -69,10 +135,25 @@ func (c *Core[T]) Get() {
 			return
 		}
 	}
+	if err := modelMeta.checkExpand(expandRelations, c.maxExpandDepth()); err != nil {
+		c.err = cError.New(cError.ErrReadExpand, err.Error(), err)
+	}
+}
+
+// readOne 查询ID为 id 的记录，没有选择字段时返回所有 allow_get 的字段，并展开关联数据
+// extraFields 为展开关联数据额外查询的外键字段，在返回前删除
+func (c *Core[T]) readOne(
+	modelMeta *RegisteredModel, id uint64, requestedFields []string, expandRelations []*expandNode,
+) (result map[string]interface{}, extraFields []string) {
+	// 检查读取字段的合法性
+	if c.checkReadParams(modelMeta, requestedFields, expandRelations); c.err != nil {
+		return nil, nil
+	}
 
 	// 构建查询
 	db := model.Use()
-	query := db.Table(c.getModel().TableName()).Where("id = ?", id).Limit(1)
+	query := c.scoped(db.Table(c.getModel().TableName())).Where("id = ?", id).Limit(1)
+	query = withDeleted(query, modelMeta, "", c.deleted)
 
 	// 选择字段
 	if len(requestedFields) == 0 { // 如果用户没有传入选择字段，那么默认返回所有allow_get的字段信息
@@ -82,95 +163,44 @@ func (c *Core[T]) Get() {
 	}
 
 	// 如果需要查询关联表的信息，则需要将外键信息查询出来
-	associations := getModelMeta(c.getModel().TableName()).Associations
-	foreignKeys := make([]string, 0)
-	if len(expandRelations) > 0 {
-		for _, foreignTableName := range expandRelations {
-			_, ok := associations[foreignTableName]
-			if !ok {
-				c.err = cError.New(cError.ErrReadRelation, nil, fmt.Errorf("关联表%s不存在", foreignTableName))
-				return
-			}
+	// 用户没有选择的外键字段在返回前删除
+	foreignKeys := modelMeta.relationColumns(expandRelations)
+	for _, column := range foreignKeys {
+		if !slices.Contains(requestedFields, column) {
+			requestedFields = append(requestedFields, column)
+			extraFields = append(extraFields, column)
+		}
+	}
 
-			requestedFields = append(requestedFields, fmt.Sprintf("%s_id", foreignTableName))
-			foreignKeys = append(foreignKeys, fmt.Sprintf("%s_id", strcase.ToSnake(foreignTableName)))
+	// 查询版本字段以及 updated_at 用于生成 ETag、Last-Modified
+	for _, field := range []*Fields{modelMeta.Version, modelMeta.updatedAtField()} {
+		if field != nil && !slices.Contains(requestedFields, field.GormFieldName) {
+			requestedFields = append(requestedFields, field.GormFieldName)
+			extraFields = append(extraFields, field.GormFieldName)
 		}
 	}
 
 	query = query.Select(requestedFields)
 
 	// 执行查询
-	var result map[string]interface{}
 	if err := query.Scan(&result).Error; err != nil {
 		c.err = cError.New(cError.ErrDBQuery, nil, err)
-		return
+		return nil, nil
 	}
 
 	if result == nil {
 		c.err = cError.New(cError.ErrReadNotFound, nil, fmt.Errorf("ID为%d的资源不存在", id))
-		return
+		return nil, nil
 	}
 
 	// 处理关联数据
 	if len(expandRelations) > 0 {
-		for _, foreignTableName := range expandRelations {
-			foreignKey, ok := getModelMeta(c.getModel().TableName()).Associations[foreignTableName]
-			if !ok {
-				c.err = cError.New(cError.ErrReadRelation, nil, fmt.Errorf("关联表%s不存在", foreignTableName))
-				return
-			}
-			if res, err := getForeignTableData(foreignTableName, cast.ToUint64(result[strcase.ToSnake(foreignKey)])); err != nil {
-				// TODO 这里错误没有处理，因为这个表关联数据没有查询并不是一个非常致命的错误，因为前面主要的数据都查询到了
-				result[foreignTableName] = nil
-			} else {
-				result[foreignTableName] = res
+		if err := modelMeta.expandRelations([]map[string]interface{}{result}, expandRelations); err != nil {
+			// TODO 这里错误没有处理，因为这个表关联数据没有查询并不是一个非常致命的错误，因为前面主要的数据都查询到了
+			for _, node := range expandRelations {
+				result[node.Relation] = nil
 			}
-
 		}
 	}
-
-	// 执行后置钩子
-	if c.afterHook != nil {
-		// TODO
-		if err := c.afterHook(c); err != nil {
-			c.err = cError.New(cError.ErrReadHookFailure, "查询后置钩子执行失败", err)
-			return
-		}
-	}
-
-	for _, key := range foreignKeys {
-		delete(result, key)
-	}
-
-	// 返回成功结果
-	HandleRes(ctx, http.StatusOK, result, "")
-}
-
-func getForeignTableData(tableName string, id uint64) (data map[string]interface{}, err error) {
-	// 构建查询
-	db := model.Use()
-	query := db.Table(tableName).Where("id = ?", id)
-
-	modelMeta := getModelMeta(tableName)
-
-	// 找出关联表中允许查询的字段
-	fields := make([]string, 0)
-	for field := range modelMeta.AllowGetFields {
-		fields = append(fields, field)
-	}
-	if len(fields) == 0 {
-		err = errors.New("关联表中没有可查询字段")
-		return
-	}
-
-	// TODO 如果关联表还有关联别的表，就应该递归处理关联的关联的表的数据，现在暂时只处理了一层
-	query = query.Select(fields)
-
-	// 执行查询
-	if err = query.Scan(&data).Error; err != nil {
-
-		return
-	}
-
-	return
+	return result, extraFields
 }
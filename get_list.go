@@ -3,210 +3,529 @@ package crud
 import (
 	"errors"
 	"fmt"
-	"github.com/iancoleman/strcase"
 	"github.com/polaris0915/go-crud/cError"
 	"github.com/polaris0915/go-crud/model"
 	"github.com/spf13/cast"
+	"gorm.io/gorm"
 	"net/http"
+	"net/url"
+	"slices"
+	"sort"
 	"strings"
+	"time"
 )
 
+// listQueryParams GetList 中有特殊含义的查询参数，不会被当作字段过滤条件
+var listQueryParams = map[string]struct{}{
+	"page": empty, "per_page": empty, "fields": empty, "expand": empty,
+	"sort_by": empty, "sort_order": empty, "sort": empty, "filter": empty,
+	"cursor": empty, "limit": empty, "count": empty,
+	"include_deleted": empty, "only_deleted": empty,
+}
+
+// listQuery 列表查询参数
+// GetList 从URL查询参数中解析，Search 从请求体中解析，两者共用 list 的查询流程
+type listQuery struct {
+	Page    int
+	PerPage int
+	Fields  []string
+	Expand  []*expandNode
+	Sort    []sortField
+	// Conditions 字段过滤条件，之间为 AND 关系
+	Conditions []filterCondition
+	// Where 过滤表达式，与 Conditions 之间为 AND 关系
+	Where *filterExpr
+
+	// CursorMode 是否使用游标分页，游标分页时忽略 Page 与 PerPage
+	CursorMode bool
+	// Cursor 上一次响应中的 next_cursor 或 prev_cursor，为空表示第一页
+	Cursor string
+	// Limit 游标分页每页的记录数
+	Limit int
+
+	// Count 统计总记录数的方式 exact、estimate、none，为空时普通分页精确统计，游标分页不统计
+	Count string
+
+	// Conditional GET 请求支持 If-None-Match，Search 为 POST 请求不支持
+	Conditional bool
+
+	// Deleted 软删除记录的查询方式，默认不返回软删除的记录
+	Deleted string
+}
+
+// normalizePage 修正分页参数
+func (q *listQuery) normalizePage() {
+	if q.Page < 1 {
+		q.Page = 1
+	}
+	if q.PerPage < 1 || q.PerPage > 100 {
+		q.PerPage = 10
+	}
+	if q.Limit < 1 || q.Limit > 100 {
+		q.Limit = 10
+	}
+}
+
+// countMode 统计总记录数的方式
+func (q *listQuery) countMode() (string, error) {
+	switch q.Count {
+	case "":
+		if q.CursorMode {
+			return countNone, nil
+		}
+		return countExact, nil
+	case countExact, countEstimate, countNone:
+		return q.Count, nil
+	}
+	return "", fmt.Errorf("count 只能是 %s、%s 或 %s", countExact, countEstimate, countNone)
+}
+
 // GetList 执行列表查询操作
 func (c *Core[T]) GetList() {
 	ctx := c.ginCtx
+	q := &listQuery{}
 
 	// 1. 解析分页参数
-	page := cast.ToInt(ctx.DefaultQuery("page", "1"))
-	perPage := cast.ToInt(ctx.DefaultQuery("per_page", "10"))
-	if page < 1 {
-		page = 1
+	q.Page = cast.ToInt(ctx.DefaultQuery("page", "1"))
+	q.PerPage = cast.ToInt(ctx.DefaultQuery("per_page", "10"))
+	// 传入 cursor 或者 limit 参数时使用游标分页，例如 ?cursor=&limit=20
+	cursor, hasCursor := ctx.GetQuery("cursor")
+	limit, hasLimit := ctx.GetQuery("limit")
+	if hasCursor || hasLimit {
+		q.CursorMode, q.Cursor, q.Limit = true, cursor, cast.ToInt(limit)
 	}
-	if perPage < 1 || perPage > 100 {
-		perPage = 10
+	q.Count = ctx.Query("count")
+	q.Conditional = true
+	q.normalizePage()
+	if q.Deleted = c.deletedQuery(); c.err != nil {
+		return
 	}
 
 	// 2. 解析字段选择参数
-	fields := ctx.Query("fields")
-	expand := ctx.Query("expand")
+	q.Fields = splitParam(ctx.Query("fields"))
+	expand, err := parseExpand(ctx.Query("expand"))
+	if err != nil {
+		c.err = cError.New(cError.ErrReadExpand, err.Error(), err)
+		return
+	}
+	q.Expand = expand
 
 	// 3. 解析排序参数
-	sortBy := ctx.Query("sort_by")
-	sortOrder := ctx.DefaultQuery("sort_order", "desc")
-	if sortOrder != "asc" && sortOrder != "desc" {
-		sortOrder = "desc"
+	if q.Sort = c.parseSortQuery(); c.err != nil {
+		return
 	}
 
-	// 4. 解析过滤参数
-	filterParams := make(map[string]interface{})
-	// 直接从 Query 中获取参数
-	for key, values := range ctx.Request.URL.Query() {
-		if len(values) > 0 {
-			filterParams[key] = values[0]
-		}
+	// 4. 获取模型元数据
+	modelMeta := getModelMeta(c.getModel().TableName())
+	if modelMeta == nil {
+		c.err = cError.New(cError.ErrReadGeneral, nil, errors.New("未找到模型元数据"))
+		return
 	}
 
-	// 调试：打印所有查询参数
-	//log.Printf("查询参数: %+v", filterParams)
+	// 5. 解析过滤参数
+	if q.Conditions, q.Where = c.parseFilterParams(modelMeta, listQueryParams); c.err != nil {
+		return
+	}
 
-	// 调试：打印所有查询参数
-	//log.Printf("查询参数: %+v", filterParams)
+	c.list(q)
+}
 
-	// 5. 获取模型元数据
+// list 执行列表查询，GetList 与 Search 共用
+func (c *Core[T]) list(q *listQuery) {
+	ctx := c.ginCtx
+
+	// 1. 获取模型元数据，嵌套路由检查父资源是否存在
 	modelMeta := getModelMeta(c.getModel().TableName())
 	if modelMeta == nil {
 		c.err = cError.New(cError.ErrReadGeneral, nil, errors.New("未找到模型元数据"))
 		return
 	}
+	if c.resolveParent(); c.err != nil {
+		return
+	}
 
-	// 6. 执行前置钩子
+	// 2. 执行前置钩子
 	if c.beforeHook != nil {
 		if err := c.beforeHook(c); err != nil {
 			c.err = cError.New(cError.ErrReadHookFailure, nil, errors.New("列表查询前置钩子执行失败"))
 			return
 		}
 	}
+	// GET 请求命中响应缓存时直接返回缓存的结果
+	var cacheKey string
+	if q.Conditional {
+		if cacheKey = c.responseCacheKey(); c.replayCache(cacheKey) {
+			return
+		}
+	}
 
-	// 7. 解析并验证字段选择
-	var requestedFields []string
-	if fields != "" {
-		requestedFields = strings.Split(fields, ",")
-		for i := range requestedFields {
-			requestedFields[i] = strings.TrimSpace(requestedFields[i])
-			if _, ok := modelMeta.AllowGetFields[requestedFields[i]]; !ok {
-				c.err = cError.New(cError.ErrReadInvalidField, nil, fmt.Errorf("不允许获取字段: %s", requestedFields[i]))
-				return
-			}
+	// 3. 验证字段选择
+	requestedFields := q.Fields
+	for _, field := range requestedFields {
+		if _, ok := modelMeta.AllowGetFields[field]; !ok {
+			c.err = cError.New(cError.ErrReadInvalidField, nil, fmt.Errorf("不允许获取字段: %s", field))
+			return
 		}
 	}
 
-	// 8. 如果没有指定字段，使用所有允许获取的字段
+	// 4. 如果没有指定字段，使用所有允许获取的字段
 	if len(requestedFields) == 0 {
 		for field := range modelMeta.AllowGetFields {
 			requestedFields = append(requestedFields, field)
 		}
 	}
 
-	// 9. 准备数据库查询
+	// 5. 准备数据库查询
 	db := model.Use().Table(c.getModel().TableName())
 
-	// 10. 处理过滤条件
-	for key, value := range filterParams {
-		// 调试：打印每个过滤条件
-		//log.Printf("处理过滤条件 - 字段: %s, 值: %v", key, value)
+	// 6. 处理过滤条件，key 记录归一化之后的过滤条件，用于缓存总记录数
+	db, key := c.applyFilters(db, modelMeta, q.Conditions, q.Where)
+	if c.err != nil {
+		return
+	}
+	db = withDeleted(db, modelMeta, "", q.Deleted)
+	// 排除软删除的记录同样是过滤条件，此时不能使用包括已删除记录的表统计信息估算
+	if modelMeta.deletedAtField() != nil && q.Deleted != deletedInclude {
+		key.add("deleted", []interface{}{q.Deleted})
+	}
 
-		// 检查是否是允许的字段
-		if _, ok := modelMeta.AllowGetFields[key]; !ok {
-			//log.Printf("跳过不允许的过滤字段: %s", key)
-			continue // 跳过不允许的过滤字段
-		}
+	// 7. 处理排序，没有指定排序时使用模型的默认排序
+	sorts := c.resolveSorts(modelMeta, q.Sort)
+	if c.err != nil {
+		return
+	}
 
-		// 将值转换为字符串
-		strValue, ok := value.(string)
-		if !ok {
-			// 如果不是字符串，使用精确匹配
-			//log.Printf("非字符串值，使用精确匹配: %s = %v", key, value)
-			db = db.Where(fmt.Sprintf("%s = ?", key), value)
-			continue
+	// 8. 展开关联数据需要用到外键字段，没有选择的外键字段在返回前删除
+	if err := modelMeta.checkExpand(q.Expand, c.maxExpandDepth()); err != nil {
+		c.err = cError.New(cError.ErrReadExpand, err.Error(), err)
+		return
+	}
+	selectedFields := append([]string{}, requestedFields...)
+	var extraFields []string
+	for _, column := range modelMeta.relationColumns(q.Expand) {
+		if !slices.Contains(selectedFields, column) {
+			selectedFields = append(selectedFields, column)
+			extraFields = append(extraFields, column)
 		}
+	}
 
-		// 忽略空字符串
-		if strValue == "" {
-			//log.Printf("忽略空字符串: %s", key)
-			continue
+	// 9. 统计总记录数
+	countMode, err := q.countMode()
+	if err != nil {
+		c.err = cError.New(cError.ErrReadPagination, err.Error(), err)
+		return
+	}
+	total, estimated := c.countTotal(db, countMode, key)
+	if c.err != nil {
+		return
+	}
+
+	// 10. 查询结果
+	var results []map[string]interface{}
+	var data interface{}
+	if q.CursorMode {
+		var pagination model.CursorPagination
+		results, pagination = c.findByCursor(db, modelMeta, q, sorts, selectedFields)
+		if c.err != nil {
+			return
+		}
+		if total != nil {
+			pagination.Total, pagination.Estimated = *total, estimated
+		} else {
+			pagination.Uncounted = true
+		}
+		data = model.CursorDataList{Data: results, Pagination: pagination}
+	} else {
+		for _, s := range sorts {
+			for _, clause := range s.orderClauses() {
+				db = db.Order(clause)
+			}
+		}
+		offset := (q.Page - 1) * q.PerPage
+		db = db.Select(selectedFields).Offset(offset).Limit(q.PerPage)
+		if err := db.Find(&results).Error; err != nil {
+			c.err = cError.New(cError.ErrDBQuery, nil, err)
+			return
 		}
 
-		// 支持特殊的查询前缀
-		if strings.HasPrefix(strValue, "like:") {
-			// 使用 LIKE 查询
-			likeValue := strings.TrimPrefix(strValue, "like:")
-			//log.Printf("模糊匹配: %s LIKE %s", key, likeValue)
-			db = db.Where(fmt.Sprintf("%s LIKE ?", key), fmt.Sprintf("%%%s%%", likeValue))
-		} else if strings.HasPrefix(strValue, "start:") {
-			// 开头匹配
-			startValue := strings.TrimPrefix(strValue, "start:")
-			//log.Printf("开头匹配: %s LIKE %s", key, startValue)
-			db = db.Where(fmt.Sprintf("%s LIKE ?", key), fmt.Sprintf("%s%%", startValue))
-		} else if strings.HasPrefix(strValue, "end:") {
-			// 结尾匹配
-			endValue := strings.TrimPrefix(strValue, "end:")
-			//log.Printf("结尾匹配: %s LIKE %s", key, endValue)
-			db = db.Where(fmt.Sprintf("%s LIKE ?", key), fmt.Sprintf("%%%s", endValue))
+		pagination := model.Pagination{
+			PerPage:     q.PerPage,
+			CurrentPage: q.Page,
+			Estimated:   estimated,
+		}
+		if total != nil {
+			pagination.Total, pagination.TotalPages = *total, model.TotalPage(*total, q.PerPage)
 		} else {
-			// 默认精确匹配
-			//log.Printf("精确匹配: %s = %s", key, strValue)
-			db = db.Where(fmt.Sprintf("%s = ?", key), value)
+			pagination.Uncounted = true
 		}
+		data = model.DataList{Data: results, Pagination: pagination}
 	}
 
-	// 11. 处理排序
-	if sortBy != "" {
-		if _, ok := modelMeta.AllowGetFields[sortBy]; !ok {
-			c.err = cError.New(cError.ErrReadSort, nil, fmt.Errorf("不允许按字段 %s 排序", sortBy))
+	// 11. 批量处理关联数据展开
+	if err := modelMeta.expandRelations(results, q.Expand); err != nil {
+		c.err = cError.New(cError.ErrReadRelation, nil, err)
+		return
+	}
+	for _, result := range results {
+		for _, field := range extraFields {
+			delete(result, field)
+		}
+	}
+
+	// 12. 执行后置钩子
+	if c.afterHook != nil {
+		if err := c.afterHook(c); err != nil {
+			c.err = cError.New(cError.ErrReadHookFailure, nil, errors.New("列表查询后置钩子执行失败"))
 			return
 		}
-		db = db.Order(fmt.Sprintf("%s %s", sortBy, sortOrder))
 	}
 
-	// 12. 计算总记录数
-	var total int64
-	countDB := db
-	if err := countDB.Count(&total).Error; err != nil {
-		c.err = cError.New(cError.ErrDBQuery, nil, err)
-		return
+	// 13. GET 请求使用当前页的数据以及分页信息计算 ETag，客户端的缓存仍然有效时返回 304
+	if q.Conditional {
+		etag, err := contentETag(data)
+		if err != nil {
+			c.err = cError.New(cError.ErrReadGeneral, nil, err)
+			return
+		}
+		c.storeCache(cacheKey, data, etag, time.Time{})
+		if notModified(ctx, etag, time.Time{}) {
+			return
+		}
 	}
 
-	// 13. 执行分页查询
-	offset := (page - 1) * perPage
-	db = db.Select(requestedFields).Offset(offset).Limit(perPage)
+	// 14. 返回结果
+	HandleRes(ctx, http.StatusOK, data, "")
+}
 
-	// 14. 查询结果
-	var results []map[string]interface{}
-	if err := db.Find(&results).Error; err != nil {
-		c.err = cError.New(cError.ErrDBQuery, nil, err)
-		return
+// parseSortQuery 从URL查询参数中解析排序
+// sort 支持多列排序，例如 sort=-created_at,display_name，优先于 sort_by 与 sort_order
+func (c *Core[T]) parseSortQuery() []sortField {
+	ctx := c.ginCtx
+	if sortParam := ctx.Query("sort"); sortParam != "" {
+		sorts, err := parseSortParam(sortParam)
+		if err != nil {
+			c.err = cError.New(cError.ErrReadSort, err.Error(), err)
+			return nil
+		}
+		return sorts
+	}
+	if sortBy := ctx.Query("sort_by"); sortBy != "" {
+		sortOrder := ctx.DefaultQuery("sort_order", "desc")
+		return []sortField{{Field: sortBy, Desc: sortOrder != "asc"}}
+	}
+	return nil
+}
+
+// resolveSorts 没有指定排序时使用模型的默认排序，并检查排序字段是否是 allow_get 字段
+func (c *Core[T]) resolveSorts(modelMeta *RegisteredModel, sorts []sortField) []sortField {
+	if len(sorts) == 0 && c.config != nil && c.config.DefaultSort != "" {
+		defaultSorts, err := parseSortParam(c.config.DefaultSort)
+		if err != nil {
+			c.err = cError.New(cError.ErrInvalidConfig, nil, err)
+			return nil
+		}
+		sorts = defaultSorts
+	}
+	for _, s := range sorts {
+		if _, ok := modelMeta.AllowGetFields[s.Field]; !ok {
+			c.err = cError.New(cError.ErrReadSort, nil, fmt.Errorf("不允许按字段 %s 排序", s.Field))
+			return nil
+		}
+	}
+	return sorts
+}
+
+// parseFilterParams 从URL查询参数中解析过滤条件，跳过 reserved 中的保留参数以及不是 allow_get 的字段
+// 同一个字段可以传入多个值，例如 created_at=gte:2026-01-01&created_at=lt:2026-02-01
+// filter 参数使用表达式语法，支持 AND/OR 分组，例如 (status==open,assignee==42);created_at>=2026-01-01
+func (c *Core[T]) parseFilterParams(
+	modelMeta *RegisteredModel, reserved map[string]struct{},
+) (conditions []filterCondition, where *filterExpr) {
+	ctx := c.ginCtx
+
+	filterParams := ctx.Request.URL.Query()
+	filterKeys := make([]string, 0, len(filterParams))
+	for key := range filterParams {
+		filterKeys = append(filterKeys, key)
 	}
+	sort.Strings(filterKeys)
+	for _, key := range filterKeys {
+		// 跳过保留的查询参数
+		if _, ok := reserved[key]; ok {
+			continue
+		}
+		// 检查是否是允许的字段
+		if _, ok := modelMeta.AllowGetFields[key]; !ok {
+			continue // 跳过不允许的过滤字段
+		}
 
-	// 15. 处理关联数据展开
-	var expandRelations []string
-	if expand != "" {
-		expandRelations = strings.Split(expand, ",")
-		for _, relation := range expandRelations {
-			relation = strings.TrimSpace(relation)
-			foreignKey, ok := modelMeta.Associations[relation]
-			if !ok {
-				c.err = cError.New(cError.ErrReadRelation, nil, fmt.Errorf("关联表 %s 不存在", relation))
-				return
+		for _, value := range filterParams[key] {
+			// 忽略空字符串
+			if value == "" {
+				continue
 			}
+			conditions = append(conditions, parseQueryCondition(key, value))
+		}
+	}
 
-			for i, result := range results {
-				if id, ok := result[fmt.Sprintf("%s_id", strcase.ToSnake(foreignKey))]; ok {
-					if relationData, err := getForeignTableData(relation, cast.ToUint64(id)); err == nil {
-						results[i][relation] = relationData
-					}
-				}
+	// net/url 会丢弃包含未编码 ; 的参数，filter 从原始查询字符串中读取
+	filter, err := rawFilterParam(ctx.Request.URL.RawQuery)
+	if err != nil {
+		c.err = cError.New(cError.ErrReadFilter, err.Error(), err)
+		return nil, nil
+	}
+	if filter != "" {
+		expr, err := parseFilterExpr(filter)
+		if err != nil {
+			c.err = newFilterError(err)
+			return nil, nil
+		}
+		where = expr
+	}
+	return conditions, where
+}
+
+// rawFilterParam 从原始查询字符串中读取 filter 参数，filter 中的 ; 可以不编码，例如 filter=a==1;b==2
+// 其他参数中包含未编码的 ; 时会被 net/url 丢弃，返回错误而不是忽略该过滤条件
+func rawFilterParam(rawQuery string) (string, error) {
+	var filter string
+	found := false
+	for _, pair := range strings.Split(rawQuery, "&") {
+		key, value, _ := strings.Cut(pair, "=")
+		name, err := url.QueryUnescape(key)
+		if err != nil {
+			return "", fmt.Errorf("无效的查询参数: %s", key)
+		}
+		if name != "filter" {
+			if strings.Contains(pair, ";") {
+				return "", fmt.Errorf("查询参数 %s 中的 ; 需要编码为 %%3B", name)
 			}
+			continue
+		}
+		if found {
+			continue
+		}
+		if filter, err = url.QueryUnescape(value); err != nil {
+			return "", errors.New("filter 参数编码错误")
 		}
+		found = true
 	}
+	return filter, nil
+}
 
-	// 16. 执行后置钩子
-	if c.afterHook != nil {
-		if err := c.afterHook(c); err != nil {
-			c.err = cError.New(cError.ErrReadHookFailure, nil, errors.New("列表查询后置钩子执行失败"))
+// newFilterError 将过滤表达式的解析错误转换为响应错误，语法错误的 detail 中包含出错的位置
+func newFilterError(err error) *cError.Error {
+	var exprErr *filterExprError
+	if errors.As(err, &exprErr) {
+		return cError.New(cError.ErrReadFilter, exprErr.Detail(), err)
+	}
+	return cError.New(cError.ErrReadFilter, err.Error(), err)
+}
+
+// applyFilters 将嵌套路由的父资源条件以及过滤条件添加到查询中
+// 返回的 key 记录归一化之后的过滤条件，用于缓存总记录数
+func (c *Core[T]) applyFilters(
+	db *gorm.DB, modelMeta *RegisteredModel, conditions []filterCondition, where *filterExpr,
+) (*gorm.DB, filterKey) {
+	var key filterKey
+	if query, args := c.scopeCondition(); query != "" {
+		db = db.Where(query, args...)
+		key.add(query, args)
+	}
+	for _, cond := range conditions {
+		query, args, err := modelMeta.buildCondition(cond)
+		if err != nil {
+			c.err = cError.New(cError.ErrReadFilter, err.Error(), err)
+			return db, nil
+		}
+		db = db.Where(query, args...)
+		key.add(query, args)
+	}
+	if where != nil {
+		query, args, err := modelMeta.buildFilterExpr(where)
+		if err != nil {
+			c.err = cError.New(cError.ErrReadFilter, err.Error(), err)
+			return db, nil
+		}
+		db = db.Where(query, args...)
+		key.add(query, args)
+	}
+	return db, key
+}
+
+// findByCursor 使用游标（keyset）分页查询
+// 排序字段之后会补充 id 作为排序依据，多查询一条记录用于判断是否还有更多数据
+func (c *Core[T]) findByCursor(
+	db *gorm.DB, modelMeta *RegisteredModel, q *listQuery, sorts []sortField, fields []string,
+) (results []map[string]interface{}, pagination model.CursorPagination) {
+	pagination.Limit = q.Limit
+
+	keys, err := modelMeta.cursorSorts(sorts)
+	if err != nil {
+		c.err = cError.New(cError.ErrReadPagination, err.Error(), err)
+		return
+	}
+
+	// 解析游标，向前翻页时反转排序方向，查询完成后再将结果反转回来
+	var cursor *listCursor
+	if q.Cursor != "" {
+		if cursor, err = modelMeta.decodeCursor(q.Cursor, keys); err != nil {
+			c.err = cError.New(cError.ErrReadPagination, err.Error(), err)
 			return
 		}
 	}
+	order := keys
+	if cursor != nil && cursor.Prev {
+		order = reverseSorts(keys)
+	}
+	if cursor != nil {
+		query, args := keysetCondition(order, cursor.Values)
+		db = db.Where(query, args...)
+	}
+	for _, s := range order {
+		for _, clause := range s.orderClauses() {
+			db = db.Order(clause)
+		}
+	}
 
-	// 17. 准备分页信息
-	pagination := model.Pagination{
-		Total:       total,
-		PerPage:     perPage,
-		CurrentPage: page,
-		TotalPages:  model.TotalPage(total, perPage),
+	// 生成游标需要用到排序字段的值，没有选择的排序字段在返回前删除
+	selected := append([]string{}, fields...)
+	var extraFields []string
+	for _, key := range keys {
+		if !slices.Contains(selected, key.Field) {
+			selected = append(selected, key.Field)
+			extraFields = append(extraFields, key.Field)
+		}
+	}
+
+	if err = db.Select(selected).Limit(q.Limit + 1).Find(&results).Error; err != nil {
+		c.err = cError.New(cError.ErrDBQuery, nil, err)
+		return
 	}
 
-	// 18. 返回结果
-	HandleRes(ctx, http.StatusOK, model.DataList{
-		Data:       results,
-		Pagination: pagination,
-	}, "")
+	hasMore := len(results) > q.Limit
+	if hasMore {
+		results = results[:q.Limit]
+	}
+	hasNext, hasPrev := hasMore, cursor != nil
+	if cursor != nil && cursor.Prev {
+		slices.Reverse(results)
+		hasNext, hasPrev = true, hasMore
+	}
+
+	if len(results) > 0 {
+		if hasNext {
+			pagination.NextCursor = encodeCursor(keys, results[len(results)-1], false)
+		}
+		if hasPrev {
+			pagination.PrevCursor = encodeCursor(keys, results[0], true)
+		}
+	}
+
+	for _, result := range results {
+		for _, field := range extraFields {
+			delete(result, field)
+		}
+	}
+	return
 }
@@ -0,0 +1,63 @@
+package crud
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newTestServer 使用以测试名称命名的共享内存数据库，创建模型的表并初始化 crud
+// 返回还没有注册路由的 gin.Engine 以及数据库，路由由测试按需注册
+func newTestServer(t *testing.T, models ...CModel) (*gin.Engine, *gorm.DB) {
+	t.Helper()
+	return openTestServer(t, fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name()), models...)
+}
+
+// newWALTestServer 与 newTestServer 相同，但使用 WAL 模式的数据库文件
+// 事务提交之前其他连接仍然可以读到旧数据，用于测试提交之前的并发查询
+func newWALTestServer(t *testing.T, models ...CModel) (*gin.Engine, *gorm.DB) {
+	t.Helper()
+	dsn := filepath.Join(t.TempDir(), "test.db") + "?_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)"
+	return openTestServer(t, dsn, models...)
+}
+
+func openTestServer(t *testing.T, dsn string, models ...CModel) (*gin.Engine, *gorm.DB) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range models {
+		if err := db.AutoMigrate(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	InitCrud(db, models...)
+	return gin.New(), db
+}
+
+// countQueries 统计数据库执行的查询次数，子查询构建时的 DryRun 不计入
+// Find、Count 走 Query 回调，Scan 走 Row 回调
+func countQueries(t *testing.T, db *gorm.DB) *int64 {
+	t.Helper()
+	var queries int64
+	count := func(tx *gorm.DB) {
+		if !tx.DryRun {
+			atomic.AddInt64(&queries, 1)
+		}
+	}
+	if err := db.Callback().Query().After("gorm:query").Register("test:count_queries", count); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Callback().Row().After("gorm:row").Register("test:count_rows", count); err != nil {
+		t.Fatal(err)
+	}
+	return &queries
+}
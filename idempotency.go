@@ -0,0 +1,334 @@
+package crud
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/polaris0915/go-crud/cError"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// IdempotencyKeyHeader 幂等键的请求头
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// idempotencyReplayedHeader 重放保存的响应时添加的响应头
+const idempotencyReplayedHeader = "Idempotent-Replayed"
+
+// defaultIdempotencyTTL 幂等键的默认过期时间
+const defaultIdempotencyTTL = 24 * time.Hour
+
+// idempotencyHeaders 除 Content-Type 之外需要保存并重放的响应头，例如创建返回的 Location 以及更新返回的 ETag
+var idempotencyHeaders = []string{"Location", "ETag", "Last-Modified"}
+
+// IdempotencyRecord 幂等键对应的请求以及第一次请求的响应
+type IdempotencyRecord struct {
+	// Key 由用户、请求方法、请求路径以及幂等键组成
+	Key string
+	// RequestHash 请求参数以及请求体的摘要，相同的幂等键用于不同的请求时返回错误
+	RequestHash string
+	// Status 响应状态码，为0时表示请求正在处理中
+	Status      int
+	ContentType string
+	// Header 需要重放的其他响应头
+	Header    map[string]string
+	Body      []byte
+	ExpiresAt time.Time
+}
+
+// IdempotencyStore 保存幂等键以及第一次请求的响应
+type IdempotencyStore interface {
+	// Reserve 幂等键不存在或者已经过期时保存 record 并返回空，否则返回已经保存的记录
+	Reserve(record *IdempotencyRecord) (*IdempotencyRecord, error)
+	// Complete 保存请求的响应
+	Complete(record *IdempotencyRecord) error
+	// Release 删除幂等键，请求失败之后可以使用相同的幂等键重试
+	Release(key string) error
+}
+
+// idempotent 返回处理 Idempotency-Key 请求头的中间件，没有配置 IdempotencyStore 时返回空
+// 请求中没有 Idempotency-Key 时不做处理，第一次请求的响应按照用户、请求路径以及幂等键保存，重试时直接返回保存的响应
+func (c *Config) idempotent() []gin.HandlerFunc {
+	if c.IdempotencyStore == nil {
+		return nil
+	}
+	ttl := c.IdempotencyTTL
+	if ttl <= 0 {
+		ttl = defaultIdempotencyTTL
+	}
+	store := c.IdempotencyStore
+
+	return []gin.HandlerFunc{func(ctx *gin.Context) {
+		key := ctx.GetHeader(IdempotencyKeyHeader)
+		if key == "" {
+			ctx.Next()
+			return
+		}
+
+		// 1. 计算请求的摘要，读取之后重新设置请求体
+		body, err := io.ReadAll(ctx.Request.Body)
+		if err != nil {
+			HandleErr(ctx, cError.New(cError.ErrInvalidRequest, nil, err))
+			ctx.Abort()
+			return
+		}
+		ctx.Request.Body = io.NopCloser(bytes.NewReader(body))
+		hash := sha256.New()
+		hash.Write([]byte(ctx.Request.URL.RawQuery + "\n"))
+		hash.Write(body)
+
+		// 2. 保存幂等键，已经存在时重放保存的响应
+		// 没有设置 user_id 时所有请求会共用幂等键，可能重放其他用户的响应，因此拒绝请求
+		scopedKey, ok := idempotencyKey(ctx, key)
+		if !ok {
+			err := errors.New("使用 Idempotency-Key 需要在中间件中设置 user_id")
+			_ = ctx.Error(err)
+			HandleErr(ctx, cError.New(cError.ErrInvalidConfig, nil, err))
+			ctx.Abort()
+			return
+		}
+		record := &IdempotencyRecord{
+			Key:         scopedKey,
+			RequestHash: hex.EncodeToString(hash.Sum(nil)),
+			ExpiresAt:   time.Now().Add(ttl),
+		}
+		existing, err := store.Reserve(record)
+		if err != nil {
+			HandleErr(ctx, cError.New(cError.ErrDBExecution, nil, err))
+			ctx.Abort()
+			return
+		}
+		if existing != nil {
+			replayIdempotent(ctx, existing, record.RequestHash)
+			ctx.Abort()
+			return
+		}
+
+		// 3. 执行请求并记录响应
+		writer := &idempotencyWriter{ResponseWriter: ctx.Writer}
+		ctx.Writer = writer
+		defer func() {
+			if r := recover(); r != nil {
+				_ = store.Release(record.Key)
+				panic(r)
+			}
+		}()
+		ctx.Next()
+
+		// 4. 服务端错误时删除幂等键，允许客户端重试，其余响应保存用于重放
+		if writer.Status() >= http.StatusInternalServerError {
+			_ = store.Release(record.Key)
+			return
+		}
+		record.Status, record.ContentType, record.Body = writer.Status(), writer.Header().Get("Content-Type"), writer.body.Bytes()
+		for _, name := range idempotencyHeaders {
+			if value := writer.Header().Get(name); value != "" {
+				if record.Header == nil {
+					record.Header = make(map[string]string)
+				}
+				record.Header[name] = value
+			}
+		}
+		if err := store.Complete(record); err != nil {
+			_ = ctx.Error(err)
+		}
+	}}
+}
+
+// idempotencyKey 幂等键按照用户、请求方法以及请求路径区分，用户为中间件中设置的 user_id
+// 没有设置 user_id 或者为空时返回 false
+func idempotencyKey(ctx *gin.Context, key string) (string, bool) {
+	user, ok := ctx.Get("user_id")
+	if !ok || user == nil || fmt.Sprint(user) == "" {
+		return "", false
+	}
+	return fmt.Sprintf("%v %s %s %s", user, ctx.Request.Method, ctx.Request.URL.Path, key), true
+}
+
+// replayIdempotent 返回保存的响应，请求不同或者第一次请求还没有完成时返回更新冲突
+func replayIdempotent(ctx *gin.Context, record *IdempotencyRecord, requestHash string) {
+	if record.RequestHash != requestHash {
+		err := errors.New("Idempotency-Key 已经用于其他请求")
+		HandleErr(ctx, cError.New(cError.ErrUpdateConflict, err.Error(), err))
+		return
+	}
+	if record.Status == 0 {
+		err := errors.New("相同 Idempotency-Key 的请求正在处理中")
+		HandleErr(ctx, cError.New(cError.ErrUpdateConflict, err.Error(), err))
+		return
+	}
+	for name, value := range record.Header {
+		ctx.Header(name, value)
+	}
+	ctx.Header(idempotencyReplayedHeader, "true")
+	ctx.Data(record.Status, record.ContentType, record.Body)
+}
+
+// idempotencyWriter 在写入响应的同时记录响应体
+type idempotencyWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *idempotencyWriter) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *idempotencyWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// MemoryIdempotencyStore 进程内的幂等键存储，只适用于单实例部署
+type MemoryIdempotencyStore struct {
+	mu        sync.Mutex
+	records   map[string]*IdempotencyRecord
+	lastSweep time.Time
+}
+
+// NewMemoryIdempotencyStore 创建进程内的幂等键存储
+func NewMemoryIdempotencyStore() *MemoryIdempotencyStore {
+	return &MemoryIdempotencyStore{records: make(map[string]*IdempotencyRecord)}
+}
+
+func (s *MemoryIdempotencyStore) Reserve(record *IdempotencyRecord) (*IdempotencyRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// 每分钟最多清理一次过期的幂等键
+	now := time.Now()
+	if now.Sub(s.lastSweep) > time.Minute {
+		for key, r := range s.records {
+			if now.After(r.ExpiresAt) {
+				delete(s.records, key)
+			}
+		}
+		s.lastSweep = now
+	}
+
+	if existing, ok := s.records[record.Key]; ok && now.Before(existing.ExpiresAt) {
+		replay := *existing
+		return &replay, nil
+	}
+	reserved := *record
+	s.records[record.Key] = &reserved
+	return nil, nil
+}
+
+func (s *MemoryIdempotencyStore) Complete(record *IdempotencyRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	completed := *record
+	s.records[record.Key] = &completed
+	return nil
+}
+
+func (s *MemoryIdempotencyStore) Release(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.records, key)
+	return nil
+}
+
+// idempotencyRow 幂等键在数据库中的记录，键较长，使用摘要作为主键
+type idempotencyRow struct {
+	ID          string `gorm:"column:id;type:char(64);primary_key"`
+	RequestHash string `gorm:"column:request_hash;type:char(64);not null"`
+	Status      int    `gorm:"column:status;not null"`
+	ContentType string `gorm:"column:content_type;type:varchar(255)"`
+	// Header 需要重放的其他响应头，JSON 格式
+	Header    string    `gorm:"column:header;type:text"`
+	Body      []byte    `gorm:"column:body"`
+	ExpiresAt time.Time `gorm:"column:expires_at;not null;index"`
+}
+
+func (r *idempotencyRow) TableName() string {
+	return "crud_idempotency_key"
+}
+
+// DBIdempotencyStore 使用数据库表 crud_idempotency_key 保存幂等键，适用于多实例部署
+type DBIdempotencyStore struct {
+	db *gorm.DB
+}
+
+// NewDBIdempotencyStore 创建数据库幂等键存储，并自动迁移 crud_idempotency_key 表
+func NewDBIdempotencyStore(db *gorm.DB) (*DBIdempotencyStore, error) {
+	if err := db.AutoMigrate(&idempotencyRow{}); err != nil {
+		return nil, err
+	}
+	return &DBIdempotencyStore{db: db}, nil
+}
+
+func (s *DBIdempotencyStore) Reserve(record *IdempotencyRecord) (*IdempotencyRecord, error) {
+	id := idempotencyRowID(record.Key)
+
+	// 1. 删除过期的幂等键
+	if err := s.db.Where("id = ? AND expires_at < ?", id, time.Now()).Delete(&idempotencyRow{}).Error; err != nil {
+		return nil, err
+	}
+
+	// 2. 幂等键不存在时插入，并发请求中只有一个可以插入成功
+	row := &idempotencyRow{ID: id, RequestHash: record.RequestHash, ExpiresAt: record.ExpiresAt}
+	result := s.db.Clauses(clause.OnConflict{DoNothing: true}).Create(row)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected > 0 {
+		return nil, nil
+	}
+
+	// 3. 返回已经存在的幂等键
+	var existing idempotencyRow
+	if err := s.db.Where("id = ?", id).Take(&existing).Error; err != nil {
+		return nil, err
+	}
+	replay := &IdempotencyRecord{
+		Key: record.Key, RequestHash: existing.RequestHash, Status: existing.Status,
+		ContentType: existing.ContentType, Body: existing.Body, ExpiresAt: existing.ExpiresAt,
+	}
+	if existing.Header != "" {
+		if err := json.Unmarshal([]byte(existing.Header), &replay.Header); err != nil {
+			return nil, err
+		}
+	}
+	return replay, nil
+}
+
+func (s *DBIdempotencyStore) Complete(record *IdempotencyRecord) error {
+	var header string
+	if len(record.Header) > 0 {
+		data, err := json.Marshal(record.Header)
+		if err != nil {
+			return err
+		}
+		header = string(data)
+	}
+	return s.db.Model(&idempotencyRow{}).Where("id = ?", idempotencyRowID(record.Key)).Updates(map[string]interface{}{
+		"status": record.Status, "content_type": record.ContentType, "header": header, "body": record.Body,
+	}).Error
+}
+
+func (s *DBIdempotencyStore) Release(key string) error {
+	return s.db.Where("id = ?", idempotencyRowID(key)).Delete(&idempotencyRow{}).Error
+}
+
+// Purge 删除所有过期的幂等键，可以定期调用
+func (s *DBIdempotencyStore) Purge() error {
+	return s.db.Where("expires_at < ?", time.Now()).Delete(&idempotencyRow{}).Error
+}
+
+func idempotencyRowID(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,110 @@
+package crud
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestIdempotency(t *testing.T) {
+	_, db := newTestServer(t, &importUser{}, &versionNote{})
+	dbStore, err := NewDBIdempotencyStore(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, store := range map[string]IdempotencyStore{"memory": NewMemoryIdempotencyStore(), "db": dbStore} {
+		t.Run(name, func(t *testing.T) {
+			db.Where("1 = 1").Delete(&importUser{})
+			r := gin.New()
+			// 幂等键按照用户区分
+			user := func(ctx *gin.Context) { ctx.Set("user_id", ctx.GetHeader("X-User")) }
+			RegisterModelApi[*importUser](r.Group("/api"), "user",
+				CreateMiddlewares(user), Idempotency(store, time.Hour))
+
+			post := func(userID, key, body string) *httptest.ResponseRecorder {
+				req := httptest.NewRequest(http.MethodPost, "/api/user", strings.NewReader(body))
+				req.Header.Set("Content-Type", "application/json")
+				req.Header.Set("X-User", userID)
+				if key != "" {
+					req.Header.Set(IdempotencyKeyHeader, key)
+				}
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				return w
+			}
+			count := func() int64 {
+				var n int64
+				db.Model(&importUser{}).Count(&n)
+				return n
+			}
+
+			first := post("1", "k1", `{"email":"a@x.com","name":"A"}`)
+			if first.Code != http.StatusCreated {
+				t.Fatalf("status = %d, body = %s", first.Code, first.Body.String())
+			}
+			retry := post("1", "k1", `{"email":"a@x.com","name":"A"}`)
+			if retry.Code != first.Code || retry.Body.String() != first.Body.String() ||
+				retry.Header().Get(idempotencyReplayedHeader) != "true" {
+				t.Errorf("retry = %d %s", retry.Code, retry.Body.String())
+			}
+			// 重放时同样返回创建时的 Location 响应头
+			if location := retry.Header().Get("Location"); location == "" || location != first.Header().Get("Location") {
+				t.Errorf("retry Location = %q, want %q", location, first.Header().Get("Location"))
+			}
+			if contentType := retry.Header().Get("Content-Type"); contentType != first.Header().Get("Content-Type") {
+				t.Errorf("retry Content-Type = %q", contentType)
+			}
+			if n := count(); n != 1 {
+				t.Errorf("count = %d, want 1", n)
+			}
+
+			// 相同的幂等键用于不同的请求体
+			if w := post("1", "k1", `{"email":"b@x.com","name":"B"}`); w.Code != http.StatusConflict {
+				t.Errorf("reused key status = %d", w.Code)
+			}
+			// 其他用户使用相同的幂等键不受影响
+			if w := post("2", "k1", `{"email":"b@x.com","name":"B"}`); w.Code != http.StatusCreated {
+				t.Errorf("other user status = %d", w.Code)
+			}
+			// 没有幂等键时不做处理
+			if w := post("1", "", `{"email":"a@x.com","name":"A"}`); w.Code != http.StatusConflict {
+				t.Errorf("no key status = %d", w.Code)
+			}
+			// 没有用户时不能与其他请求共用幂等键，拒绝请求
+			if w := post("", "k1", `{"email":"c@x.com","name":"C"}`); w.Code != http.StatusInternalServerError || w.Header().Get(idempotencyReplayedHeader) != "" {
+				t.Errorf("no user status = %d", w.Code)
+			}
+			if n := count(); n != 2 {
+				t.Errorf("count = %d, want 2", n)
+			}
+		})
+	}
+	// 重放更新请求时同样返回更新时的 ETag
+	t.Run("etag", func(t *testing.T) {
+		db.Create(&versionNote{ID: 1, Body: "a", UpdatedAt: time.Now().Add(-time.Hour)})
+		r := gin.New()
+		user := func(ctx *gin.Context) { ctx.Set("user_id", 1) }
+		RegisterModelApi[*versionNote](r.Group("/api"), "note",
+			UpdateMiddlewares(user), Idempotency(NewMemoryIdempotencyStore(), time.Hour))
+		patch := func() *httptest.ResponseRecorder {
+			req := httptest.NewRequest(http.MethodPatch, "/api/note/1", strings.NewReader(`{"body":"b"}`))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set(IdempotencyKeyHeader, "k1")
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			return w
+		}
+		first := patch()
+		if first.Code != http.StatusOK || first.Header().Get("ETag") == "" {
+			t.Fatalf("status = %d, ETag = %q", first.Code, first.Header().Get("ETag"))
+		}
+		if etag := patch().Header().Get("ETag"); etag != first.Header().Get("ETag") {
+			t.Errorf("retry ETag = %q, want %q", etag, first.Header().Get("ETag"))
+		}
+	})
+}
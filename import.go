@@ -0,0 +1,379 @@
+package crud
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/polaris0915/go-crud/cError"
+	"github.com/polaris0915/go-crud/model"
+	"github.com/spf13/cast"
+	"gorm.io/gorm"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+// on_conflict 唯一字段与已有记录重复时的处理方式
+const (
+	conflictFail   = "fail"
+	conflictSkip   = "skip"
+	conflictUpdate = "update"
+)
+
+// 单条记录的导入结果
+const (
+	importCreated = "created"
+	importUpdated = "updated"
+	importSkipped = "skipped"
+)
+
+// errImportRecord 单条记录解析失败，可以继续读取之后的记录
+var errImportRecord = errors.New("记录解析错误")
+
+// importRowError 导入失败的记录
+type importRowError struct {
+	// Row 记录的序号，从1开始，csv 不包括表头
+	Row     int         `json:"row"`
+	Code    int         `json:"code"`
+	Message string      `json:"message"`
+	Detail  interface{} `json:"detail,omitempty"`
+}
+
+// importReport 导入结果
+type importReport struct {
+	Total   int  `json:"total"`
+	Created int  `json:"created"`
+	Updated int  `json:"updated"`
+	Skipped int  `json:"skipped"`
+	Failed  int  `json:"failed"`
+	DryRun  bool `json:"dry_run"`
+	// RolledBack 全部成功模式下有记录导入失败时回滚了所有记录
+	RolledBack bool             `json:"rolled_back"`
+	Errors     []importRowError `json:"errors"`
+}
+
+// importReader 逐条读取导入的记录，读取完成时返回 io.EOF
+// 单条记录解析失败时返回 errImportRecord，可以继续读取之后的记录，其他错误无法继续读取
+type importReader interface {
+	next() (map[string]interface{}, error)
+}
+
+// newImportReader 根据 Content-Type 创建 importReader，支持 csv、JSON 数组以及 ndjson
+func newImportReader(ctx *gin.Context) (importReader, error) {
+	switch ctx.ContentType() {
+	case "text/csv":
+		return newCSVImportReader(ctx.Request.Body)
+	case exportContentTypes[exportNDJSON], "application/ndjson":
+		scanner := bufio.NewScanner(ctx.Request.Body)
+		scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+		return &ndjsonImportReader{scanner: scanner}, nil
+	case "application/json", "":
+		var items []json.RawMessage
+		if err := json.NewDecoder(ctx.Request.Body).Decode(&items); err != nil {
+			return nil, errors.New("请求体必须是 JSON 数组")
+		}
+		return &jsonImportReader{items: items}, nil
+	}
+	return nil, fmt.Errorf("不支持的 Content-Type: %s", ctx.ContentType())
+}
+
+// Import 批量导入记录，每条记录与 Create 使用相同的校验、唯一性检查以及模型转换
+// 创建的记录执行创建钩子，on_conflict=update 时更新的记录执行更新钩子
+// 查询参数:
+//   - dry_run=true 只校验不写入
+//   - on_conflict=fail|skip|update 唯一字段与已有记录重复时报错、跳过或者使用允许部分更新的字段更新已有记录，默认为 fail
+//   - atomic=true|false 全部成功（所有记录在同一个事务中，有记录失败时全部回滚）或者尽量导入（每条记录单独提交），默认为 true
+func (c *Core[T]) Import() {
+	ctx := c.ginCtx
+
+	// 1. 嵌套路由检查父资源是否存在
+	if c.resolveParent(); c.err != nil {
+		return
+	}
+
+	// 2. 解析导入参数
+	dryRun := cast.ToBool(ctx.Query("dry_run"))
+	atomic := cast.ToBool(ctx.DefaultQuery("atomic", "true"))
+	onConflict := ctx.DefaultQuery("on_conflict", conflictFail)
+	if onConflict != conflictFail && onConflict != conflictSkip && onConflict != conflictUpdate {
+		err := fmt.Errorf("on_conflict 只能是 %s、%s 或 %s", conflictFail, conflictSkip, conflictUpdate)
+		c.err = cError.New(cError.ErrCreateImport, err.Error(), err)
+		return
+	}
+	reader, err := newImportReader(ctx)
+	if err != nil {
+		c.err = cError.New(cError.ErrCreateImport, err.Error(), err)
+		return
+	}
+
+	// 3. 全部成功模式下所有记录在同一个事务中导入，每条记录使用一个保存点
+	// 尽量导入模式下每条记录单独使用一个事务
+	db := model.Use()
+	if atomic && !dryRun {
+		db = model.Use().Begin()
+		if db.Error != nil {
+			c.err = cError.New(cError.ErrDBTransaction, nil, db.Error)
+			return
+		}
+	}
+	inTransaction := atomic && !dryRun
+
+	// 4. 逐条导入，全部成功模式下有记录失败之后只校验不再写入
+	report := &importReport{DryRun: dryRun, Errors: []importRowError{}}
+	// seen 记录没有写入数据库的记录中唯一字段的值，用于检查文件中的记录之间是否重复
+	seen := make(map[string]map[string]int)
+	for {
+		payload, err := reader.next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil && !errors.Is(err, errImportRecord) {
+			if inTransaction {
+				db.Rollback()
+			}
+			c.err = cError.New(cError.ErrCreateImport, err.Error(), err)
+			return
+		}
+		report.Total++
+
+		var result string
+		var rowErr *cError.Error
+		if err != nil {
+			rowErr = cError.New(cError.ErrCreateMissingField, err.Error(), err)
+		} else {
+			write := !dryRun && !(atomic && report.Failed > 0)
+			result, rowErr = c.importRow(db, payload, onConflict, write, seen, report.Total)
+		}
+
+		if rowErr != nil {
+			report.Failed++
+			report.Errors = append(report.Errors, importRowError{
+				Row: report.Total, Code: rowErr.Code, Message: rowErr.Message, Detail: rowErr.PublicDetail(),
+			})
+			continue
+		}
+		switch result {
+		case importCreated:
+			report.Created++
+		case importUpdated:
+			report.Updated++
+		case importSkipped:
+			report.Skipped++
+		}
+	}
+
+	// 5. 提交或者回滚全部成功模式的事务
+	if inTransaction {
+		if report.Failed > 0 {
+			db.Rollback()
+			report.RolledBack, report.Created, report.Updated = true, 0, 0
+		} else if err := db.Commit().Error; err != nil {
+			c.err = cError.New(cError.ErrDBTransaction, nil, err)
+			return
+		}
+	}
+	if !dryRun && report.Created+report.Updated > 0 {
+		invalidateModel(c.getModel().TableName())
+	}
+
+	// 6. 返回导入结果
+	HandleRes(ctx, http.StatusOK, report, "")
+}
+
+// importRow 使用 db 导入第 index 条记录，write 为 false 时只校验不写入
+// 不写入时之前的记录也没有写入数据库，使用 seen 检查记录是否与文件中之前的记录重复
+func (c *Core[T]) importRow(
+	db *gorm.DB, payload map[string]interface{}, onConflict string, write bool,
+	seen map[string]map[string]int, index int,
+) (string, *cError.Error) {
+	// 1. 每条记录使用单独的核心对象，钩子函数中获取到的是当前记录的数据
+	row := c.itemCore(payload)
+
+	// 2. 校验请求数据
+	if row.validateCreate(); row.err != nil {
+		return "", row.err
+	}
+
+	// 3. 检查唯一性约束
+	id, err := findDuplicate(db, func() CModel { return c.getModel() }, row.payload)
+	duplicated := errors.Is(err, errDataDuplicated)
+	if err != nil && !duplicated {
+		return "", cError.New(cError.ErrCreateGeneral, nil, err)
+	}
+	if !duplicated && !write {
+		modelMeta := getModelMeta(c.getModel().TableName())
+		if previous, ok := batchDuplicate(seen, modelMeta, row.payload, index); ok {
+			if onConflict == conflictFail {
+				err := fmt.Errorf("与第 %d 条记录重复", previous)
+				return "", cError.New(cError.ErrCreateDuplicate, err.Error(), errDataDuplicated)
+			}
+			duplicated = true
+		}
+	}
+	if duplicated {
+		switch onConflict {
+		case conflictSkip:
+			return importSkipped, nil
+		case conflictFail:
+			return "", cError.New(cError.ErrCreateDuplicate, nil, errDataDuplicated)
+		}
+	}
+
+	// 4. 转换为模型并执行前置钩子，更新重复的记录时使用更新的钩子
+	if duplicated && c.config != nil {
+		row.beforeHook, row.afterHook = c.config.BeforeUpdate, c.config.AfterUpdate
+	}
+	if row.decodeCreate(); row.err != nil {
+		return "", row.err
+	}
+	result := importCreated
+	if duplicated {
+		result = importUpdated
+	}
+	if !write {
+		return result, nil
+	}
+
+	// 5. 创建记录或者更新重复的记录，并执行后置钩子，失败时只回滚当前记录
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if duplicated {
+			row.updateDuplicate(tx, id)
+		} else {
+			row.insert(tx)
+		}
+		if row.err != nil {
+			return row.err
+		}
+		return nil
+	})
+	if row.err != nil {
+		return "", row.err
+	}
+	if err != nil {
+		return "", cError.New(cError.ErrDBTransaction, nil, err)
+	}
+	return result, nil
+}
+
+// updateDuplicate 使用允许部分更新的字段更新唯一字段重复的记录，并执行更新后置钩子
+// 嵌套路由中重复的记录不属于当前父资源时不更新
+func (c *Core[T]) updateDuplicate(tx *gorm.DB, id uint64) {
+	// 1. 检查重复的记录是否属于当前父资源
+	var count int64
+	if err := c.scoped(tx.Model(c.getModel())).Where("id = ?", id).Count(&count).Error; err != nil {
+		c.err = cError.New(cError.ErrDBQuery, nil, err)
+		return
+	}
+	if count == 0 {
+		c.err = cError.New(cError.ErrCreateDuplicate, nil, errDataDuplicated)
+		return
+	}
+
+	// 2. 使用转换之后的模型中允许部分更新的字段更新记录
+	modelMeta := getModelMeta(c.getModel().TableName())
+	value := reflect.ValueOf(c.model).Elem()
+	columns := make(map[string]interface{})
+	for key := range c.payload {
+		if _, ok := modelMeta.PartialUpdateFields[key]; !ok {
+			continue
+		}
+		if field := modelMeta.fieldByJsonTag(key); field != nil {
+			columns[field.GormFieldName] = value.FieldByName(field.Name).Interface()
+		}
+	}
+	if len(columns) > 0 {
+		if err := tx.Model(c.getModel()).Where("id = ?", id).Updates(columns).Error; err != nil {
+			c.err = cError.New(cError.ErrDBExecution, nil, err)
+			return
+		}
+	}
+
+	if c.afterHook != nil {
+		if err := c.afterHook(c); err != nil {
+			c.err = cError.New(cError.ErrUpdateHookFailure, nil, errors.New("更新后置钩子函数执行失败"))
+		}
+	}
+}
+
+// csvImportReader 读取 csv 格式的记录，第一行为字段的json标签，空单元格表示没有该字段
+type csvImportReader struct {
+	reader *csv.Reader
+	header []string
+}
+
+func newCSVImportReader(r io.Reader) (*csvImportReader, error) {
+	reader := csv.NewReader(r)
+	header, err := reader.Read()
+	if err != nil {
+		return nil, errors.New("csv 缺少表头")
+	}
+	// 去掉导出时写入的 UTF-8 BOM
+	header[0] = strings.TrimPrefix(header[0], "\uFEFF")
+	for i := range header {
+		header[i] = strings.TrimSpace(header[i])
+	}
+	return &csvImportReader{reader: reader, header: header}, nil
+}
+
+func (r *csvImportReader) next() (map[string]interface{}, error) {
+	record, err := r.reader.Read()
+	if err != nil {
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			return nil, fmt.Errorf("%w: %v", errImportRecord, err)
+		}
+		return nil, err
+	}
+	payload := make(map[string]interface{}, len(record))
+	for i, value := range record {
+		if value != "" {
+			payload[r.header[i]] = value
+		}
+	}
+	return payload, nil
+}
+
+// ndjsonImportReader 读取 ndjson 格式的记录，每行一个 JSON 对象，忽略空行
+type ndjsonImportReader struct {
+	scanner *bufio.Scanner
+}
+
+func (r *ndjsonImportReader) next() (map[string]interface{}, error) {
+	for r.scanner.Scan() {
+		line := strings.TrimSpace(r.scanner.Text())
+		if line == "" {
+			continue
+		}
+		var payload map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &payload); err != nil || payload == nil {
+			return nil, fmt.Errorf("%w: 不是有效的 JSON 对象", errImportRecord)
+		}
+		return payload, nil
+	}
+	if err := r.scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, io.EOF
+}
+
+// jsonImportReader 读取 JSON 数组中的记录
+type jsonImportReader struct {
+	items []json.RawMessage
+}
+
+func (r *jsonImportReader) next() (map[string]interface{}, error) {
+	if len(r.items) == 0 {
+		return nil, io.EOF
+	}
+	item := r.items[0]
+	r.items = r.items[1:]
+	var payload map[string]interface{}
+	if err := json.Unmarshal(item, &payload); err != nil || payload == nil {
+		return nil, fmt.Errorf("%w: 不是有效的 JSON 对象", errImportRecord)
+	}
+	return payload, nil
+}
@@ -0,0 +1,199 @@
+package crud
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/polaris0915/go-crud/cError"
+)
+
+type importUser struct {
+	ID    uint64 `gorm:"column:id;primary_key" json:"id"`
+	Email string `gorm:"column:email;type:varchar(100);unique;not null" json:"email" crud:"required_on_create,allow_get"`
+	Name  string `gorm:"column:name;type:varchar(50)" json:"name" crud:"required_on_create,allow_get,partial_update"`
+	Age   int    `gorm:"column:age" json:"age" crud:"allow_get,partial_update"`
+}
+
+func (u *importUser) TableName() string { return "import_user" }
+
+type importReportResponse struct {
+	Code int          `json:"code"`
+	Data importReport `json:"data"`
+}
+
+func TestImport(t *testing.T) {
+	r, db := newTestServer(t, &importUser{})
+
+	// 前置钩子拒绝名称为 blocked 的记录，后置钩子记录创建以及更新的邮箱
+	var hooked, updated []string
+	RegisterModelApi[*importUser](r.Group("/api"), "user",
+		BeforeCreate(func(core ICore) error {
+			if core.GetPayload()["name"] == "blocked" {
+				return errors.New("blocked")
+			}
+			return nil
+		}),
+		AfterCreate(func(core ICore) error {
+			hooked = append(hooked, core.GetModel().(*importUser).Email)
+			return nil
+		}),
+		AfterUpdate(func(core ICore) error {
+			updated = append(updated, core.GetModel().(*importUser).Email)
+			return nil
+		}),
+	)
+
+	doImport := func(t *testing.T, query, contentType, body string) importReport {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/api/user/import?"+query, strings.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d, body = %s", w.Code, w.Body.String())
+		}
+		var resp importReportResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		return resp.Data
+	}
+	users := func() []string {
+		var rows []importUser
+		db.Order("id").Find(&rows)
+		var result []string
+		for _, row := range rows {
+			result = append(result, fmt.Sprintf("%s:%s:%d", row.Email, row.Name, row.Age))
+		}
+		return result
+	}
+	errorRows := func(report importReport) map[int]int {
+		codes := make(map[int]int)
+		for _, e := range report.Errors {
+			codes[e.Row] = e.Code
+		}
+		return codes
+	}
+
+	t.Run("csv", func(t *testing.T) {
+		body := "\uFEFFemail,name,age\na@x.com,A,20\nb@x.com,B,\n"
+		report := doImport(t, "", "text/csv", body)
+		if report.Total != 2 || report.Created != 2 || report.Failed != 0 {
+			t.Errorf("report = %+v", report)
+		}
+		if want := []string{"a@x.com:A:20", "b@x.com:B:0"}; !reflect.DeepEqual(users(), want) {
+			t.Errorf("users = %v, want %v", users(), want)
+		}
+		if want := []string{"a@x.com", "b@x.com"}; !reflect.DeepEqual(hooked, want) {
+			t.Errorf("hooked = %v, want %v", hooked, want)
+		}
+	})
+
+	t.Run("atomic rollback", func(t *testing.T) {
+		// 第2条缺少必填字段，第3条与已有记录重复，第4条与事务中已经创建的第1条重复，第5条被前置钩子拒绝
+		body := `[{"email":"c@x.com","name":"C"},{"email":"d@x.com"},{"email":"a@x.com","name":"A2"},` +
+			`{"email":"c@x.com","name":"C2"},{"email":"e@x.com","name":"blocked"}]`
+		report := doImport(t, "", "application/json", body)
+		if !report.RolledBack || report.Created != 0 || report.Failed != 4 {
+			t.Errorf("report = %+v", report)
+		}
+		want := map[int]int{
+			2: cError.ErrCreateMissingField, 3: cError.ErrCreateDuplicate,
+			4: cError.ErrCreateDuplicate, 5: cError.ErrCreateHookFailure,
+		}
+		if got := errorRows(report); !reflect.DeepEqual(got, want) {
+			t.Errorf("errors = %v, want %v", got, want)
+		}
+		if len(users()) != 2 {
+			t.Errorf("users = %v", users())
+		}
+	})
+
+	t.Run("best effort", func(t *testing.T) {
+		// 第4条与第1条重复，第1条已经提交，可以检查出重复
+		body := "{\"email\":\"c@x.com\",\"name\":\"C\"}\nnot json\n\n{\"email\":\"a@x.com\",\"name\":\"A2\"}\n" +
+			"{\"email\":\"c@x.com\",\"name\":\"C2\"}\n"
+		report := doImport(t, "atomic=false", "application/x-ndjson", body)
+		if report.RolledBack || report.Total != 4 || report.Created != 1 || report.Failed != 3 {
+			t.Errorf("report = %+v", report)
+		}
+		want := map[int]int{2: cError.ErrCreateMissingField, 3: cError.ErrCreateDuplicate, 4: cError.ErrCreateDuplicate}
+		if got := errorRows(report); !reflect.DeepEqual(got, want) {
+			t.Errorf("errors = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("on conflict", func(t *testing.T) {
+		body := `[{"email":"a@x.com","name":"A3","age":30},{"email":"f@x.com","name":"F"}]`
+		report := doImport(t, "on_conflict=skip&dry_run=true", "application/json", body)
+		if !report.DryRun || report.Skipped != 1 || report.Created != 1 {
+			t.Errorf("dry run report = %+v", report)
+		}
+		if len(users()) != 3 {
+			t.Errorf("dry run wrote users: %v", users())
+		}
+
+		hooked = nil
+		report = doImport(t, "on_conflict=update", "application/json", body)
+		if report.Updated != 1 || report.Created != 1 {
+			t.Errorf("report = %+v", report)
+		}
+		// 更新的记录执行更新钩子
+		if !reflect.DeepEqual(hooked, []string{"f@x.com"}) || !reflect.DeepEqual(updated, []string{"a@x.com"}) {
+			t.Errorf("create hooks = %v, update hooks = %v", hooked, updated)
+		}
+		want := []string{"a@x.com:A3:30", "b@x.com:B:0", "c@x.com:C:0", "f@x.com:F:0"}
+		if !reflect.DeepEqual(users(), want) {
+			t.Errorf("users = %v, want %v", users(), want)
+		}
+	})
+
+	t.Run("dry run duplicates", func(t *testing.T) {
+		// 第2条与第1条重复，第4条与已有记录重复，只校验时也要与实际导入的结果一致
+		body := `[{"email":"g@x.com","name":"G"},{"email":"g@x.com","name":"G2"},{"email":"h@x.com","name":"H"},{"email":"a@x.com","name":"A"}]`
+		for _, tc := range []struct {
+			onConflict                string
+			created, updated, skipped int
+			errors                    map[int]int
+		}{
+			{"fail", 2, 0, 0, map[int]int{2: cError.ErrCreateDuplicate, 4: cError.ErrCreateDuplicate}},
+			{"skip", 2, 0, 2, map[int]int{}},
+			{"update", 2, 2, 0, map[int]int{}},
+		} {
+			report := doImport(t, "atomic=false&dry_run=true&on_conflict="+tc.onConflict, "application/json", body)
+			if report.Created != tc.created || report.Updated != tc.updated || report.Skipped != tc.skipped ||
+				!reflect.DeepEqual(errorRows(report), tc.errors) {
+				t.Errorf("%s: report = %+v", tc.onConflict, report)
+			}
+		}
+		if len(users()) != 4 {
+			t.Errorf("dry run wrote users: %v", users())
+		}
+
+		report := doImport(t, "atomic=false", "application/json", body)
+		want := map[int]int{2: cError.ErrCreateDuplicate, 4: cError.ErrCreateDuplicate}
+		if report.Created != 2 || !reflect.DeepEqual(errorRows(report), want) {
+			t.Errorf("report = %+v", report)
+		}
+	})
+
+	for _, tc := range []struct{ query, contentType, body string }{
+		{"on_conflict=replace", "application/json", `[]`},
+		{"", "application/json", `{"email":"g@x.com"}`},
+		{"", "application/xml", `<user/>`},
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/api/user/import?"+tc.query, strings.NewReader(tc.body))
+		req.Header.Set("Content-Type", tc.contentType)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s %s: status = %d, body = %s", tc.query, tc.contentType, w.Code, w.Body.String())
+		}
+	}
+}
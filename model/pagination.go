@@ -1,10 +1,27 @@
 package model
 
+import "encoding/json"
+
 type Pagination struct {
 	Total       int64 `json:"total"`
 	PerPage     int   `json:"per_page"`
 	CurrentPage int   `json:"current_page"`
 	TotalPages  int64 `json:"total_pages"`
+	// Estimated 总记录数是否是估算值
+	Estimated bool `json:"estimated,omitempty"`
+	// Uncounted 没有统计总记录数（count=none），序列化时省略 total 与 total_pages
+	Uncounted bool `json:"-"`
+}
+
+func (p Pagination) MarshalJSON() ([]byte, error) {
+	type pagination Pagination
+	if !p.Uncounted {
+		return json.Marshal(pagination(p))
+	}
+	return json.Marshal(struct {
+		PerPage     int `json:"per_page"`
+		CurrentPage int `json:"current_page"`
+	}{p.PerPage, p.CurrentPage})
 }
 
 type DataList struct {
@@ -12,6 +29,37 @@ type DataList struct {
 	Pagination Pagination  `json:"pagination,omitempty"`
 }
 
+// CursorPagination 游标分页信息
+type CursorPagination struct {
+	Limit int `json:"limit"`
+	// NextCursor 下一页的游标，没有下一页时为空
+	NextCursor string `json:"next_cursor,omitempty"`
+	// PrevCursor 上一页的游标，没有上一页时为空
+	PrevCursor string `json:"prev_cursor,omitempty"`
+	// Total 总记录数，只有指定 count=exact 或 count=estimate 时才会统计
+	Total     int64 `json:"total"`
+	Estimated bool  `json:"estimated,omitempty"`
+	// Uncounted 没有统计总记录数，序列化时省略 total
+	Uncounted bool `json:"-"`
+}
+
+func (p CursorPagination) MarshalJSON() ([]byte, error) {
+	type pagination CursorPagination
+	if !p.Uncounted {
+		return json.Marshal(pagination(p))
+	}
+	return json.Marshal(struct {
+		Limit      int    `json:"limit"`
+		NextCursor string `json:"next_cursor,omitempty"`
+		PrevCursor string `json:"prev_cursor,omitempty"`
+	}{p.Limit, p.NextCursor, p.PrevCursor})
+}
+
+type CursorDataList struct {
+	Data       interface{}      `json:"data"`
+	Pagination CursorPagination `json:"pagination"`
+}
+
 // TotalPage calculate total page
 func TotalPage(total int64, pageSize int) int64 {
 	// fix: divide by zero
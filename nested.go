@@ -0,0 +1,119 @@
+package crud
+
+import (
+	"errors"
+	"fmt"
+	"github.com/polaris0915/go-crud/cError"
+	"github.com/polaris0915/go-crud/model"
+	"github.com/spf13/cast"
+	"gorm.io/gorm"
+	"strings"
+)
+
+// nestedParent 嵌套路由中的父资源
+type nestedParent struct {
+	// getModel 父模型的工厂函数
+	getModel func() CModel
+	// Relation 父模型中子资源的关联名称
+	Relation string
+}
+
+// parentScope 当前请求中父资源对子资源的限定条件
+type parentScope struct {
+	// Column 子资源中指向父资源的外键列，Value 为父资源对应列的值
+	Column string
+	Value  interface{}
+	// PolymorphicColumn 多态关联中子资源记录类型的列以及父资源对应的值
+	PolymorphicColumn string
+	PolymorphicValue  string
+}
+
+// resourceID 路径中的资源ID，嵌套路由中 id 为父资源的ID，子资源的ID为 child_id
+func (c *Core[T]) resourceID() uint64 {
+	if c.parent != nil {
+		return cast.ToUint64(c.ginCtx.Param("child_id"))
+	}
+	return cast.ToUint64(c.ginCtx.Param("id"))
+}
+
+// resolveParent 检查嵌套路由中的父资源是否存在，并生成子资源的限定条件，不是嵌套路由时直接返回
+func (c *Core[T]) resolveParent() {
+	if c.parent == nil {
+		return
+	}
+
+	// 1. 获取父模型中子资源的关联关系
+	parentModel := c.parent.getModel()
+	parentMeta := getModelMeta(parentModel.TableName())
+	if parentMeta == nil {
+		c.err = cError.New(cError.ErrInvalidConfig, nil, fmt.Errorf("父模型 %s 没有注册", parentModel.TableName()))
+		return
+	}
+	a, ok := parentMeta.Associations[c.parent.Relation]
+	if !ok || a.Table != c.getModel().TableName() {
+		c.err = cError.New(cError.ErrInvalidConfig, nil,
+			fmt.Errorf("父模型 %s 中没有子资源 %s 的关联", parentModel.TableName(), c.parent.Relation))
+		return
+	}
+	if a.Type != hasOne && a.Type != hasMany {
+		c.err = cError.New(cError.ErrInvalidConfig, nil, errors.New("嵌套路由只支持 has_one、has_many 关联"))
+		return
+	}
+
+	// 2. 检查父资源是否存在
+	parentID := cast.ToUint64(c.ginCtx.Param("id"))
+	if parentID == 0 {
+		c.err = cError.New(cError.ErrReadInvalidID, nil, errors.New("父资源ID不能为空"))
+		return
+	}
+	var row map[string]interface{}
+	err := model.Use().Model(parentModel).Select(a.OwnColumn).Where("id = ?", parentID).Limit(1).Find(&row).Error
+	if err != nil {
+		c.err = cError.New(cError.ErrDBQuery, nil, err)
+		return
+	}
+	if len(row) == 0 {
+		c.err = cError.New(cError.ErrReadNotFound, nil, fmt.Errorf("ID为%d的父资源不存在", parentID))
+		return
+	}
+
+	c.scope = &parentScope{
+		Column:            a.RelatedColumn,
+		Value:             row[a.OwnColumn],
+		PolymorphicColumn: a.PolymorphicColumn,
+		PolymorphicValue:  a.PolymorphicValue,
+	}
+}
+
+// scopeCondition 父资源对子资源的查询条件，不是嵌套路由时返回空字符串
+func (c *Core[T]) scopeCondition() (query string, args []interface{}) {
+	if c.scope == nil {
+		return "", nil
+	}
+	conditions := []string{fmt.Sprintf("%s = ?", c.scope.Column)}
+	args = append(args, c.scope.Value)
+	if c.scope.PolymorphicColumn != "" {
+		conditions = append(conditions, fmt.Sprintf("%s = ?", c.scope.PolymorphicColumn))
+		args = append(args, c.scope.PolymorphicValue)
+	}
+	return strings.Join(conditions, " AND "), args
+}
+
+// scoped 为查询添加父资源的限定条件
+func (c *Core[T]) scoped(db *gorm.DB) *gorm.DB {
+	if query, args := c.scopeCondition(); query != "" {
+		return db.Where(query, args...)
+	}
+	return db
+}
+
+// injectScope 将父资源的外键写入到请求数据中，子资源不能指定其他的父资源
+func (c *Core[T]) injectScope(data map[string]interface{}) {
+	if c.scope == nil {
+		return
+	}
+	data[c.scope.Column] = c.scope.Value
+	if c.scope.PolymorphicColumn != "" {
+		data[c.scope.PolymorphicColumn] = c.scope.PolymorphicValue
+	}
+}
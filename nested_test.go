@@ -0,0 +1,104 @@
+package crud
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNestedModelApi(t *testing.T) {
+	r, db := newTestServer(t, &expandMember{}, &expandUpload{}, &expandRole{}, &expandImage{})
+
+	db.Create(&expandMember{ID: 1, Name: "alice"})
+	db.Create(&expandMember{ID: 2, Name: "bob"})
+	db.Create(&expandUpload{ID: 1, FileName: "alice.txt", UploaderID: 1})
+	db.Create(&expandUpload{ID: 2, FileName: "bob.txt", UploaderID: 2})
+
+	api := r.Group("/api")
+	RegisterModelApi[*expandMember](api, "member")
+	RegisterNestedModelApi[*expandMember, *expandUpload](api, "member", "files")
+
+	do := func(method, url, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(method, url, strings.NewReader(body)))
+		return w
+	}
+	listFiles := func(url string) []string {
+		t.Helper()
+		var res struct {
+			Data struct {
+				Data []map[string]interface{} `json:"data"`
+			} `json:"data"`
+		}
+		doExpandRequest(t, r, url, &res)
+		var names []string
+		for _, row := range res.Data.Data {
+			names = append(names, row["file_name"].(string))
+		}
+		return names
+	}
+
+	// 创建时写入父资源的外键，请求中指定的外键会被忽略
+	if w := do(http.MethodPost, "/api/member/1/files", `{"file_name":"new.txt","uploader_id":2}`); w.Code != http.StatusCreated {
+		t.Fatalf("create: status %d, body %s", w.Code, w.Body.String())
+	}
+	var created expandUpload
+	db.Where("file_name = ?", "new.txt").First(&created)
+	if created.UploaderID != 1 {
+		t.Errorf("expected uploader_id 1, got %d", created.UploaderID)
+	}
+
+	// 列表只返回父资源的子资源
+	if got := listFiles("/api/member/1/files?sort=id"); strings.Join(got, ",") != "alice.txt,new.txt" {
+		t.Errorf("unexpected files %v", got)
+	}
+	if got := listFiles("/api/member/2/files"); strings.Join(got, ",") != "bob.txt" {
+		t.Errorf("unexpected files %v", got)
+	}
+
+	// 父资源的路由不受影响
+	var member struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	doExpandRequest(t, r, "/api/member/1?fields=name", &member)
+	if member.Data["name"] != "alice" {
+		t.Errorf("unexpected member %v", member.Data)
+	}
+
+	// 其他父资源的子资源以及不存在的父资源都返回404
+	for _, req := range []struct{ method, url, body string }{
+		{http.MethodGet, "/api/member/1/files/2", ""},
+		{http.MethodPatch, "/api/member/1/files/2", `{"file_name":"x"}`},
+		{http.MethodDelete, "/api/member/1/files/2", ""},
+		{http.MethodGet, "/api/member/9/files", ""},
+		{http.MethodPost, "/api/member/9/files", `{"file_name":"x"}`},
+	} {
+		if w := do(req.method, req.url, req.body); w.Code != http.StatusNotFound {
+			t.Errorf("%s %s: expected status 404, got %d, body %s", req.method, req.url, w.Code, w.Body.String())
+		}
+	}
+
+	// 更新时不能修改为其他父资源
+	w := do(http.MethodPatch, "/api/member/1/files/1", `{"file_name":"renamed.txt","uploader_id":2}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("update: status %d, body %s", w.Code, w.Body.String())
+	}
+	var updated struct {
+		Data expandUpload `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &updated); err != nil {
+		t.Fatal(err)
+	}
+	if updated.Data.FileName != "renamed.txt" || updated.Data.UploaderID != 1 {
+		t.Errorf("unexpected update result %+v", updated.Data)
+	}
+
+	if w := do(http.MethodDelete, "/api/member/1/files/1", ""); w.Code != http.StatusNoContent {
+		t.Errorf("delete: status %d, body %s", w.Code, w.Body.String())
+	}
+	if got := listFiles("/api/member/1/files"); strings.Join(got, ",") != "new.txt" {
+		t.Errorf("unexpected files after delete %v", got)
+	}
+}
@@ -2,6 +2,7 @@ package crud
 
 import (
 	"github.com/gin-gonic/gin"
+	"time"
 )
 
 // HookFunc 定义db操作前的hook行为
@@ -26,6 +27,10 @@ type Config struct {
 	BeforeUpdate      HookFunc
 	AfterUpdate       HookFunc
 
+	RestoreMiddlewares []gin.HandlerFunc
+	BeforeRestore      HookFunc
+	AfterRestore       HookFunc
+
 	GetMiddlewares []gin.HandlerFunc
 	BeforeGet      HookFunc
 	AfterGet       HookFunc
@@ -33,6 +38,24 @@ type Config struct {
 	GetListMiddlewares []gin.HandlerFunc
 	BeforeGetList      HookFunc
 	AfterGetList       HookFunc
+	// DefaultSort 列表查询没有指定排序时使用的默认排序，格式与 sort 参数相同
+	DefaultSort string
+	// CountCacheTTL 列表查询总记录数的缓存时间，为0时不缓存
+	CountCacheTTL time.Duration
+	// MaxExpandDepth 关联展开的最大深度，为0时使用默认值3
+	MaxExpandDepth int
+	// MaxAffected 批量更新、批量删除一次最多影响的记录数，为0时使用默认值1000
+	MaxAffected int
+	// IdempotencyStore 保存 Idempotency-Key 以及第一次请求的响应，为空时不处理 Idempotency-Key
+	IdempotencyStore IdempotencyStore
+	// IdempotencyTTL 幂等键的过期时间，为0时使用默认值24小时
+	IdempotencyTTL time.Duration
+	// Cache Get、GetList 的响应缓存，为空时不缓存
+	Cache Cache
+	// CacheTTL 响应缓存的过期时间，为0时只在模型发生变化时失效
+	CacheTTL time.Duration
+	// TrashAccess 检查当前请求是否可以通过 include_deleted、only_deleted 查看软删除的记录，为空时不允许
+	TrashAccess func(ctx *gin.Context) bool
 }
 
 // CreateMiddlewares 添加进入创建路由前的钩子，例如权限验证等
@@ -63,6 +86,13 @@ func GetMiddlewares(handlers ...gin.HandlerFunc) Option {
 	}
 }
 
+// GetListMiddlewares 添加进入列表查询路由前的钩子，例如权限验证等
+func GetListMiddlewares(handlers ...gin.HandlerFunc) Option {
+	return func(c *Config) {
+		c.GetListMiddlewares = append(c.GetListMiddlewares, handlers...)
+	}
+}
+
 // BeforeCreate 添加创建数据前的钩子
 func BeforeCreate(hook HookFunc) Option {
 	return func(c *Config) {
@@ -118,3 +148,90 @@ func AfterGet(hook HookFunc) Option {
 		c.AfterGet = hook
 	}
 }
+
+// BeforeGetList 添加列表查询前的钩子
+func BeforeGetList(hook HookFunc) Option {
+	return func(c *Config) {
+		c.BeforeGetList = hook
+	}
+}
+
+// AfterGetList 添加列表查询后的钩子
+func AfterGetList(hook HookFunc) Option {
+	return func(c *Config) {
+		c.AfterGetList = hook
+	}
+}
+
+// DefaultSort 设置列表查询的默认排序，例如 DefaultSort("-created_at,id")
+func DefaultSort(sort string) Option {
+	return func(c *Config) {
+		c.DefaultSort = sort
+	}
+}
+
+// CountCache 开启列表查询总记录数的进程内缓存，模型发生创建、更新、删除时缓存失效
+func CountCache(ttl time.Duration) Option {
+	return func(c *Config) {
+		c.CountCacheTTL = ttl
+	}
+}
+
+// MaxExpandDepth 设置关联展开的最大深度，例如 expand=author.department 的深度为2
+func MaxExpandDepth(depth int) Option {
+	return func(c *Config) {
+		c.MaxExpandDepth = depth
+	}
+}
+
+// MaxAffected 设置批量更新、批量删除一次最多影响的记录数，请求中的 max_affected 参数只能比该值小
+func MaxAffected(n int) Option {
+	return func(c *Config) {
+		c.MaxAffected = n
+	}
+}
+
+// Idempotency 开启 POST、PATCH 请求的 Idempotency-Key 支持，ttl 为0时幂等键24小时后过期
+// 幂等键按照中间件中通过 ctx.Set("user_id", ...) 设置的用户以及请求路径区分，需要在设置 user_id 的中间件之后执行
+// 带有 Idempotency-Key 的请求没有设置 user_id 时返回 1006 无效配置，不处理请求；不区分用户时可以设置一个固定的 user_id
+func Idempotency(store IdempotencyStore, ttl time.Duration) Option {
+	return func(c *Config) {
+		c.IdempotencyStore, c.IdempotencyTTL = store, ttl
+	}
+}
+
+// ResponseCache 开启 Get、GetList 的响应缓存，缓存按照模型、资源ID或查询参数、user_id 区分
+// 模型以及通过关联关系引用了该模型的模型发生创建、更新、删除时缓存失效
+func ResponseCache(cache Cache, ttl time.Duration) Option {
+	return func(c *Config) {
+		c.Cache, c.CacheTTL = cache, ttl
+	}
+}
+
+// TrashAccess 设置查看软删除记录的权限检查，例如只允许管理员通过 include_deleted、only_deleted 查看回收站
+func TrashAccess(check func(ctx *gin.Context) bool) Option {
+	return func(c *Config) {
+		c.TrashAccess = check
+	}
+}
+
+// RestoreMiddlewares 添加进入恢复软删除记录路由前的钩子，例如权限验证等
+func RestoreMiddlewares(handlers ...gin.HandlerFunc) Option {
+	return func(c *Config) {
+		c.RestoreMiddlewares = append(c.RestoreMiddlewares, handlers...)
+	}
+}
+
+// BeforeRestore 添加恢复软删除记录前的钩子，批量恢复时可以通过 GetAffectedIDs 获取恢复的记录ID
+func BeforeRestore(hook HookFunc) Option {
+	return func(c *Config) {
+		c.BeforeRestore = hook
+	}
+}
+
+// AfterRestore 添加恢复软删除记录后的钩子，与恢复在同一个事务中执行
+func AfterRestore(hook HookFunc) Option {
+	return func(c *Config) {
+		c.AfterRestore = hook
+	}
+}
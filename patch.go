@@ -0,0 +1,404 @@
+package crud
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/polaris0915/go-crud/cError"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// 更新请求体的 Content-Type
+const (
+	contentTypeJSONPatch  = "application/json-patch+json"
+	contentTypeMergePatch = "application/merge-patch+json"
+)
+
+// errPatchTest JSON Patch 中 test 操作的值与当前记录不同
+var errPatchTest = errors.New("test 操作失败")
+
+// jsonPatchOp JSON Patch 中的单个操作
+type jsonPatchOp struct {
+	Op   string `json:"op"`
+	Path string `json:"path"`
+	From string `json:"from"`
+	// Value 为空时表示没有 value 成员，null 为 JSON 的 null
+	Value json.RawMessage `json:"value"`
+}
+
+// bindPatch 根据 Content-Type 解析部分更新的请求数据
+// JSON Patch 以及 JSON Merge Patch 作用于当前记录，只能修改 partial_update 的字段，结果转换为需要更新的字段
+// JSON 列中的对象以及数组转换为 JSON 字符串写入数据库
+func (c *Core[T]) bindPatch(existing T) map[string]interface{} {
+	contentType := c.ginCtx.ContentType()
+	if contentType != contentTypeJSONPatch && contentType != contentTypeMergePatch {
+		return c.bindJSON(existing)
+	}
+	modelMeta := getModelMeta(c.getModel().TableName())
+
+	// 1. 将当前记录转换为 JSON 文档
+	doc, err := toJSONDocument(existing)
+	if err != nil {
+		c.err = cError.New(cError.ErrUpdateGeneral, nil, err)
+		return nil
+	}
+
+	// 2. 根据 Content-Type 修改文档，记录修改的字段
+	var changed []string
+	if contentType == contentTypeJSONPatch {
+		var ops []jsonPatchOp
+		if err := c.ginCtx.ShouldBindJSON(&ops); err != nil {
+			c.err = cError.New(cError.ErrUpdateValidation, "请求体必须是 JSON Patch 操作数组", err)
+			return nil
+		}
+		changed, err = applyJSONPatch(doc, ops, modelMeta)
+	} else {
+		var patch map[string]interface{}
+		if err := c.ginCtx.ShouldBindJSON(&patch); err != nil {
+			c.err = cError.New(cError.ErrUpdateValidation, "请求体必须是 JSON 对象", err)
+			return nil
+		}
+		changed, err = applyMergePatch(doc, patch, modelMeta)
+	}
+	if err != nil {
+		var fieldErr *patchFieldError
+		switch {
+		case errors.As(err, &fieldErr):
+			c.err = cError.New(cError.ErrUpdateInvalidField, nil, err)
+		case errors.Is(err, errPatchTest):
+			c.err = cError.New(cError.ErrUpdateConflict, err.Error(), err)
+		default:
+			c.err = cError.New(cError.ErrUpdateValidation, err.Error(), err)
+		}
+		return nil
+	}
+
+	// 3. 修改的字段转换为更新的值，删除的字段更新为零值
+	data := make(map[string]interface{}, len(changed))
+	for _, key := range changed {
+		field := modelMeta.fieldByJsonTag(key)
+		value, ok := doc[key]
+		if !ok || value == nil {
+			data[key] = reflect.Zero(field.Type).Interface()
+			continue
+		}
+		if data[key], err = columnValue(field, value); err != nil {
+			c.err = cError.New(cError.ErrUpdateValidation, nil, err)
+			return nil
+		}
+	}
+	return data
+}
+
+// patchFieldError 修改了不允许部分更新的字段
+type patchFieldError struct {
+	field string
+}
+
+func (e *patchFieldError) Error() string {
+	return fmt.Sprintf("字段 '%s' 不支持更新操作", e.field)
+}
+
+// toJSONDocument 将模型转换为 JSON 对象
+func toJSONDocument(m CModel) (map[string]interface{}, error) {
+	raw, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// columnValue JSON 文档中的值转换为写入数据库的值
+// 对象以及数组转换为字段的类型，例如 json.RawMessage、datatypes.JSON，字符串类型的字段转换为 JSON 字符串
+func columnValue(field *Fields, value interface{}) (interface{}, error) {
+	switch value.(type) {
+	case map[string]interface{}, []interface{}:
+	default:
+		return value, nil
+	}
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	if field.Type.Kind() == reflect.String {
+		return string(raw), nil
+	}
+	target := reflect.New(field.Type)
+	if err := json.Unmarshal(raw, target.Interface()); err != nil {
+		return nil, err
+	}
+	return target.Elem().Interface(), nil
+}
+
+// applyMergePatch 使用 JSON Merge Patch 修改文档，返回修改的字段
+// 值为 null 时清空字段，值为对象并且字段当前也是对象时递归合并，其余情况直接替换
+func applyMergePatch(doc, patch map[string]interface{}, modelMeta *RegisteredModel) ([]string, error) {
+	var changed []string
+	for key, value := range patch {
+		if _, ok := modelMeta.PartialUpdateFields[key]; !ok {
+			return nil, &patchFieldError{field: key}
+		}
+		if value == nil {
+			delete(doc, key)
+		} else {
+			doc[key] = mergePatch(doc[key], value)
+		}
+		changed = append(changed, key)
+	}
+	return changed, nil
+}
+
+// mergePatch RFC 7396 中的 MergePatch 算法
+func mergePatch(target, patch interface{}) interface{} {
+	patchObj, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	targetObj, ok := target.(map[string]interface{})
+	if !ok {
+		targetObj = make(map[string]interface{})
+	}
+	for key, value := range patchObj {
+		if value == nil {
+			delete(targetObj, key)
+		} else {
+			targetObj[key] = mergePatch(targetObj[key], value)
+		}
+	}
+	return targetObj
+}
+
+// applyJSONPatch 按顺序执行 JSON Patch 操作修改文档，返回修改的字段
+// 修改的路径只能在 partial_update 的字段中，test 以及 copy 的源路径可以是 allow_get 的字段
+func applyJSONPatch(doc map[string]interface{}, ops []jsonPatchOp, modelMeta *RegisteredModel) ([]string, error) {
+	if len(ops) == 0 {
+		return nil, errors.New("JSON Patch 操作不能为空")
+	}
+	var changed []string
+	seen := make(map[string]struct{})
+	// writable 解析修改的路径，并记录修改的字段
+	writable := func(path string) ([]string, error) {
+		tokens, err := parsePointer(path)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := modelMeta.PartialUpdateFields[tokens[0]]; !ok {
+			return nil, &patchFieldError{field: tokens[0]}
+		}
+		if _, ok := seen[tokens[0]]; !ok {
+			seen[tokens[0]] = empty
+			changed = append(changed, tokens[0])
+		}
+		return tokens, nil
+	}
+	// readable 解析读取的路径
+	readable := func(path string) ([]string, error) {
+		tokens, err := parsePointer(path)
+		if err != nil {
+			return nil, err
+		}
+		_, partial := modelMeta.PartialUpdateFields[tokens[0]]
+		_, get := modelMeta.AllowGetFields[tokens[0]]
+		if !partial && !get {
+			return nil, &patchFieldError{field: tokens[0]}
+		}
+		return tokens, nil
+	}
+	var root interface{} = doc
+
+	for i, op := range ops {
+		var value interface{}
+		if op.Op == "add" || op.Op == "replace" || op.Op == "test" {
+			if len(op.Value) == 0 {
+				return nil, fmt.Errorf("第 %d 个操作缺少 value", i+1)
+			}
+			if err := json.Unmarshal(op.Value, &value); err != nil {
+				return nil, fmt.Errorf("第 %d 个操作的 value 无效", i+1)
+			}
+		}
+
+		var err error
+		switch op.Op {
+		case "add", "replace", "remove":
+			var path []string
+			if path, err = writable(op.Path); err == nil {
+				root, err = patchAt(root, path, op.Op, value)
+			}
+		case "move", "copy":
+			var from, path []string
+			if op.Op == "move" {
+				from, err = writable(op.From)
+			} else {
+				from, err = readable(op.From)
+			}
+			if err != nil {
+				return nil, err
+			}
+			if path, err = writable(op.Path); err != nil {
+				return nil, err
+			}
+			if op.Op == "move" && op.Path != op.From && strings.HasPrefix(op.Path, op.From+"/") {
+				return nil, fmt.Errorf("第 %d 个操作不能移动到自身的子路径", i+1)
+			}
+			if value, err = pointerGet(root, from); err != nil {
+				break
+			}
+			if op.Op == "move" {
+				if root, err = patchAt(root, from, "remove", nil); err != nil {
+					break
+				}
+			} else {
+				value = deepCopyJSON(value)
+			}
+			root, err = patchAt(root, path, "add", value)
+		case "test":
+			var path []string
+			var current interface{}
+			if path, err = readable(op.Path); err == nil {
+				if current, err = pointerGet(root, path); err == nil && !reflect.DeepEqual(current, value) {
+					err = fmt.Errorf("%w: %s", errPatchTest, op.Path)
+				}
+			}
+		default:
+			err = fmt.Errorf("不支持的操作 %q", op.Op)
+		}
+		if err != nil {
+			var fieldErr *patchFieldError
+			if errors.As(err, &fieldErr) || errors.Is(err, errPatchTest) {
+				return nil, err
+			}
+			return nil, fmt.Errorf("第 %d 个操作失败: %w", i+1, err)
+		}
+	}
+	return changed, nil
+}
+
+// parsePointer 解析 JSON Pointer（RFC 6901），不允许指向整个文档
+func parsePointer(path string) ([]string, error) {
+	if !strings.HasPrefix(path, "/") {
+		return nil, fmt.Errorf("无效的路径 %q", path)
+	}
+	tokens := strings.Split(path[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+// pointerGet 获取路径指向的值
+func pointerGet(node interface{}, tokens []string) (interface{}, error) {
+	for _, token := range tokens {
+		switch n := node.(type) {
+		case map[string]interface{}:
+			value, ok := n[token]
+			if !ok {
+				return nil, fmt.Errorf("路径 %q 不存在", token)
+			}
+			node = value
+		case []interface{}:
+			index, err := arrayIndex(token, len(n)-1)
+			if err != nil {
+				return nil, err
+			}
+			node = n[index]
+		default:
+			return nil, fmt.Errorf("路径 %q 不存在", token)
+		}
+	}
+	return node, nil
+}
+
+// patchAt 在路径上执行 add、replace 或者 remove，返回修改之后的节点
+// 数组插入、删除元素时会生成新的切片，因此需要使用返回值替换原来的节点
+func patchAt(node interface{}, tokens []string, op string, value interface{}) (interface{}, error) {
+	token := tokens[0]
+	last := len(tokens) == 1
+
+	switch n := node.(type) {
+	case map[string]interface{}:
+		child, ok := n[token]
+		if !last {
+			if !ok {
+				return nil, fmt.Errorf("路径 %q 不存在", token)
+			}
+			newChild, err := patchAt(child, tokens[1:], op, value)
+			if err != nil {
+				return nil, err
+			}
+			n[token] = newChild
+			return n, nil
+		}
+		if !ok && op != "add" {
+			return nil, fmt.Errorf("路径 %q 不存在", token)
+		}
+		if op == "remove" {
+			delete(n, token)
+		} else {
+			n[token] = value
+		}
+		return n, nil
+
+	case []interface{}:
+		if last && op == "add" {
+			index := len(n)
+			if token != "-" {
+				var err error
+				if index, err = arrayIndex(token, len(n)); err != nil {
+					return nil, err
+				}
+			}
+			n = append(n[:index], append([]interface{}{value}, n[index:]...)...)
+			return n, nil
+		}
+		index, err := arrayIndex(token, len(n)-1)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case !last:
+			if n[index], err = patchAt(n[index], tokens[1:], op, value); err != nil {
+				return nil, err
+			}
+		case op == "remove":
+			n = append(n[:index], n[index+1:]...)
+		default:
+			n[index] = value
+		}
+		return n, nil
+	}
+	return nil, fmt.Errorf("路径 %q 不存在", token)
+}
+
+// arrayIndex 解析数组下标，下标必须在 0 到 max 之间
+func arrayIndex(token string, max int) (int, error) {
+	index, err := strconv.Atoi(token)
+	if err != nil || index < 0 || index > max || (len(token) > 1 && token[0] == '0') {
+		return 0, fmt.Errorf("无效的数组下标 %q", token)
+	}
+	return index, nil
+}
+
+// deepCopyJSON 复制 JSON 值，copy 操作之后修改副本不影响源路径
+func deepCopyJSON(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		copied := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			copied[key] = deepCopyJSON(item)
+		}
+		return copied
+	case []interface{}:
+		copied := make([]interface{}, len(v))
+		for i, item := range v {
+			copied[i] = deepCopyJSON(item)
+		}
+		return copied
+	}
+	return value
+}
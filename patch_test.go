@@ -0,0 +1,104 @@
+package crud
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type patchItem struct {
+	ID    uint64          `gorm:"column:id;primary_key" json:"id"`
+	Name  string          `gorm:"column:name" json:"name" crud:"allow_get,partial_update"`
+	Title string          `gorm:"column:title" json:"title" crud:"allow_get,partial_update"`
+	Code  string          `gorm:"column:code" json:"code" crud:"allow_get"`
+	Meta  json.RawMessage `gorm:"column:meta" json:"meta" crud:"allow_get,partial_update"`
+}
+
+func (p *patchItem) TableName() string { return "patch_item" }
+
+func TestUpdatePatch(t *testing.T) {
+	r, db := newTestServer(t, &patchItem{})
+	RegisterModelApi[*patchItem](r.Group("/api"), "item")
+
+	reset := func() {
+		db.Where("1 = 1").Delete(&patchItem{})
+		db.Create(&patchItem{
+			ID: 1, Name: "a", Title: "t", Code: "c1",
+			Meta: json.RawMessage(`{"color":"red","size":{"w":1,"h":2},"tags":["x","y"]}`),
+		})
+	}
+	patch := func(contentType, body string) int {
+		req := httptest.NewRequest(http.MethodPatch, "/api/item/1", strings.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+	current := func() (patchItem, map[string]interface{}) {
+		var item patchItem
+		db.First(&item, 1)
+		var meta map[string]interface{}
+		if err := json.Unmarshal(item.Meta, &meta); err != nil {
+			t.Fatalf("meta = %s: %v", item.Meta, err)
+		}
+		return item, meta
+	}
+
+	t.Run("merge patch", func(t *testing.T) {
+		reset()
+		body := `{"title":null,"meta":{"color":null,"size":{"h":3},"tags":["z"]}}`
+		if code := patch(contentTypeMergePatch, body); code != http.StatusOK {
+			t.Fatalf("status = %d", code)
+		}
+		item, meta := current()
+		want := map[string]interface{}{"size": map[string]interface{}{"w": float64(1), "h": float64(3)}, "tags": []interface{}{"z"}}
+		if item.Title != "" || item.Name != "a" || !reflect.DeepEqual(meta, want) {
+			t.Errorf("item = %+v, meta = %v", item, meta)
+		}
+	})
+
+	t.Run("json patch", func(t *testing.T) {
+		reset()
+		body := `[
+			{"op":"test","path":"/meta/color","value":"red"},
+			{"op":"replace","path":"/name","value":"b"},
+			{"op":"add","path":"/meta/tags/1","value":"new"},
+			{"op":"remove","path":"/meta/tags/0"},
+			{"op":"move","from":"/meta/size/w","path":"/meta/width"},
+			{"op":"copy","from":"/code","path":"/title"}
+		]`
+		if code := patch(contentTypeJSONPatch, body); code != http.StatusOK {
+			t.Fatalf("status = %d", code)
+		}
+		item, meta := current()
+		want := map[string]interface{}{
+			"color": "red", "size": map[string]interface{}{"h": float64(2)},
+			"tags": []interface{}{"new", "y"}, "width": float64(1),
+		}
+		if item.Name != "b" || item.Title != "c1" || !reflect.DeepEqual(meta, want) {
+			t.Errorf("item = %+v, meta = %v", item, meta)
+		}
+	})
+
+	for _, tc := range []struct {
+		contentType, body string
+		code              int
+	}{
+		{contentTypeJSONPatch, `[{"op":"test","path":"/name","value":"zz"},{"op":"replace","path":"/name","value":"c"}]`, http.StatusConflict},
+		{contentTypeJSONPatch, `[{"op":"replace","path":"/code","value":"c2"}]`, http.StatusBadRequest},
+		{contentTypeJSONPatch, `[{"op":"remove","path":"/meta/missing"}]`, http.StatusBadRequest},
+		{contentTypeJSONPatch, `{"op":"replace"}`, http.StatusBadRequest},
+		{contentTypeMergePatch, `{"code":"c2"}`, http.StatusBadRequest},
+	} {
+		reset()
+		if code := patch(tc.contentType, tc.body); code != tc.code {
+			t.Errorf("%s: status = %d, want %d", tc.body, code, tc.code)
+		}
+		if item, _ := current(); item.Name != "a" || item.Code != "c1" {
+			t.Errorf("%s: item changed: %+v", tc.body, item)
+		}
+	}
+}
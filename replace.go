@@ -0,0 +1,89 @@
+package crud
+
+import (
+	"errors"
+	"fmt"
+	"github.com/polaris0915/go-crud/cError"
+	"github.com/polaris0915/go-crud/model"
+	"gorm.io/gorm"
+	"reflect"
+	"strings"
+)
+
+// Replace 执行整体替换操作（PUT），与 Update 使用相同的存在性检查、事务以及钩子
+// 请求数据中必须包含所有 required_on_create 的字段，没有的可写字段重置为默认值或者零值
+func (c *Core[T]) Replace() {
+	c.update(c.bindJSON, c.prepareReplace)
+}
+
+// prepareReplace 检查整体替换的请求数据，并补全没有的可写字段
+// 可写字段为 required_on_create 以及 partial_update 的字段
+func (c *Core[T]) prepareReplace(modelMeta *RegisteredModel, data map[string]interface{}) {
+	if modelMeta == nil {
+		c.err = cError.New(cError.ErrUpdateGeneral, nil, fmt.Errorf("无法获取模型为%s的元数据", c.getModel().TableName()))
+		return
+	}
+
+	// 1. 检查请求数据中的字段都是可写字段，并且包含所有必填字段
+	writable := func(jsonTag string) bool {
+		_, required := modelMeta.RequireOnCreateFields[jsonTag]
+		_, partial := modelMeta.PartialUpdateFields[jsonTag]
+		return required || partial
+	}
+	for field := range data {
+		if !writable(field) {
+			c.err = cError.New(cError.ErrUpdateInvalidField, nil, fmt.Errorf("字段 '%s' 不支持更新操作", field))
+			return
+		}
+	}
+	for _, field := range modelMeta.Fields {
+		if _, ok := modelMeta.RequireOnCreateFields[field.JsonTag]; !ok {
+			continue
+		}
+		if _, ok := data[field.JsonTag]; !ok {
+			c.err = cError.New(cError.ErrUpdateMissingField, nil, fmt.Errorf("缺少必填字段 '%s'", field.JsonTag))
+			return
+		}
+	}
+
+	// 2. 没有的可写字段重置为 gorm 标签中的默认值，没有默认值时为零值
+	var omitted []*Fields
+	defaults := make(map[string]interface{})
+	for _, field := range modelMeta.Fields {
+		if _, ok := data[field.JsonTag]; ok || !writable(field.JsonTag) {
+			continue
+		}
+		omitted = append(omitted, field)
+		if field.Default != "" {
+			defaults[field.JsonTag] = strings.Trim(field.Default, `'"`)
+		}
+	}
+	reset := c.getModel()
+	if err := weakDecode(defaults, &reset); err != nil {
+		c.err = cError.New(cError.ErrUpdateGeneral, nil, err)
+		return
+	}
+	value := reflect.Indirect(reflect.ValueOf(reset))
+	// unique 记录需要检查唯一性的字段，重置为零值的字段不检查，否则会与其他同样为零值的记录冲突
+	unique := make(map[string]interface{}, len(data))
+	for jsonTag, val := range data {
+		unique[jsonTag] = val
+	}
+	for _, field := range omitted {
+		fieldValue := value.FieldByName(field.Name)
+		data[field.JsonTag] = fieldValue.Interface()
+		if !fieldValue.IsZero() {
+			unique[field.JsonTag] = fieldValue.Interface()
+		}
+	}
+
+	// 3. 唯一字段不能与同一个父资源下的其他记录重复
+	others := c.scoped(model.Use()).Where("id <> ?", c.resourceID()).Session(&gorm.Session{})
+	if _, err := findDuplicate(others, func() CModel { return c.getModel() }, unique); err != nil {
+		if errors.Is(err, errDataDuplicated) {
+			c.err = cError.New(cError.ErrUpdateConflict, "唯一字段与其他记录重复", errDataDuplicated)
+			return
+		}
+		c.err = cError.New(cError.ErrUpdateGeneral, nil, err)
+	}
+}
@@ -0,0 +1,95 @@
+package crud
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type replaceUser struct {
+	ID    uint64 `gorm:"column:id;primary_key" json:"id"`
+	Email string `gorm:"column:email;type:varchar(100);unique;not null" json:"email" crud:"required_on_create,allow_get"`
+	Name  string `gorm:"column:name;type:varchar(50)" json:"name" crud:"allow_get,partial_update"`
+	Level int    `gorm:"column:level;default:3" json:"level" crud:"allow_get,partial_update"`
+	Note  string `gorm:"column:note" json:"note" crud:"allow_get"`
+}
+
+func (u *replaceUser) TableName() string { return "replace_user" }
+
+func TestReplace(t *testing.T) {
+	r, db := newTestServer(t, &replaceUser{})
+	RegisterModelApi[*replaceUser](r.Group("/api"), "user")
+
+	db.Create(&replaceUser{Email: "a@x.com", Name: "A", Level: 9, Note: "kept"})
+	db.Create(&replaceUser{Email: "b@x.com", Name: "B", Level: 1})
+
+	put := func(path, body string) int {
+		req := httptest.NewRequest(http.MethodPut, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// name、level 没有传入，分别重置为零值以及默认值，note 不可写保持不变
+	if code := put("/api/user/1", `{"email":"c@x.com"}`); code != http.StatusOK {
+		t.Fatalf("status = %d", code)
+	}
+	var user replaceUser
+	db.First(&user, 1)
+	if got := fmt.Sprintf("%s:%s:%d:%s", user.Email, user.Name, user.Level, user.Note); got != "c@x.com::3:kept" {
+		t.Errorf("user = %s", got)
+	}
+
+	for _, tc := range []struct {
+		path, body string
+		code       int
+	}{
+		{"/api/user/1", `{"name":"A"}`, http.StatusBadRequest},
+		{"/api/user/1", `{"email":"c@x.com","note":"x"}`, http.StatusBadRequest},
+		{"/api/user/1", `{"email":"b@x.com"}`, http.StatusConflict},
+		{"/api/user/9", `{"email":"d@x.com"}`, http.StatusNotFound},
+	} {
+		if code := put(tc.path, tc.body); code != tc.code {
+			t.Errorf("%s %s: status = %d, want %d", tc.path, tc.body, code, tc.code)
+		}
+	}
+}
+
+type replaceDoc struct {
+	ID    uint64 `gorm:"column:id;primary_key" json:"id"`
+	Title string `gorm:"column:title" json:"title" crud:"required_on_create,allow_get"`
+	Slug  string `gorm:"column:slug;unique" json:"slug" crud:"allow_get,partial_update"`
+}
+
+func (d *replaceDoc) TableName() string { return "replace_doc" }
+
+func TestReplaceOmittedUnique(t *testing.T) {
+	// 表中没有唯一索引，由接口检查唯一字段，多条记录的 slug 可以同时为空
+	r, db := newTestServer(t)
+	if err := db.Exec("CREATE TABLE replace_doc (id integer PRIMARY KEY, title text, slug text)").Error; err != nil {
+		t.Fatal(err)
+	}
+	InitCrud(db, &replaceDoc{})
+	RegisterModelApi[*replaceDoc](r.Group("/api"), "doc")
+	db.Create(&replaceDoc{ID: 1, Title: "a"})
+	db.Create(&replaceDoc{ID: 2, Title: "b"})
+	db.Create(&replaceDoc{ID: 3, Title: "c", Slug: "c"})
+
+	put := func(path, body string) int {
+		req := httptest.NewRequest(http.MethodPut, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+	// 没有传入的 slug 重置为空，不与其他 slug 为空的记录冲突
+	if code := put("/api/doc/1", `{"title":"a2"}`); code != http.StatusOK {
+		t.Errorf("omitted slug: status = %d", code)
+	}
+	if code := put("/api/doc/1", `{"title":"a2","slug":"c"}`); code != http.StatusConflict {
+		t.Errorf("duplicate slug: status = %d", code)
+	}
+}
@@ -2,9 +2,12 @@
 package crud
 
 import (
+	"fmt"
 	"github.com/iancoleman/strcase"
+	"gorm.io/gorm/schema"
 	"reflect"
 	"strings"
+	"sync"
 )
 
 // empty 仅做一个占位，表示这个字段在这个要求中需要
@@ -37,10 +40,46 @@ type RegisteredModel struct {
 	PartialUpdateFields   map[string]struct{}
 	AllowGetFields        map[string]struct{}
 
-	// Associations 存储关联关系的所有信息
+	// Version 乐观锁的版本字段，为带有 version 标签的字段，没有时为 updated_at 字段，都没有时为空
+	Version *Fields
+	// RequireIfMatch 模型带有 version 标签时，更新、删除必须带有 If-Match 请求头
+	RequireIfMatch bool
+
+	// Associations 存储关联关系的所有信息，键为关联字段的json标签
 	// 例如 User表关联Role表
-	// 数据形式为: map["role"] = "RoleID"
-	Associations map[string]string
+	// 数据形式为: map["role"] = &association{Type: belongsTo, Table: "role", OwnColumn: "role_id", RelatedColumn: "id"}
+	Associations map[string]*association
+}
+
+// 关联关系类型
+const (
+	belongsTo = string(schema.BelongsTo)
+	hasOne    = string(schema.HasOne)
+	hasMany   = string(schema.HasMany)
+	many2many = string(schema.Many2Many)
+)
+
+// association 模型的关联关系，由 GORM 解析模型得到
+type association struct {
+	Type string
+	// Table 关联表名
+	Table string
+	// OwnColumn 当前表中用于匹配关联数据的列，belongs_to 为外键，其余为主键
+	OwnColumn string
+	// RelatedColumn 关联表中用于匹配的列，has_one、has_many 为外键，其余为主键
+	RelatedColumn string
+	// JoinTable many2many 的中间表，以及中间表中指向当前表和关联表的外键
+	JoinTable         string
+	JoinOwnColumn     string
+	JoinRelatedColumn string
+	// PolymorphicColumn 多态关联中记录类型的列（many2many 在中间表中），PolymorphicValue 为当前模型对应的值
+	PolymorphicColumn string
+	PolymorphicValue  string
+}
+
+// many 关联数据是否是列表
+func (a *association) many() bool {
+	return a.Type == hasMany || a.Type == many2many
 }
 
 func register(model ...CModel) {
@@ -84,6 +123,9 @@ func deepResolve(r *RegisteredModel, m reflect.Type) {
 					r.PartialUpdateFields[modelFields.JsonTag] = empty
 					r.Rules["update"][modelFields.JsonTag] = "partial_update"
 				}
+				if tag == "version" {
+					r.Version, r.RequireIfMatch = modelFields, true
+				}
 				if tag == "allow_get" {
 					r.AllowGetFields[modelFields.JsonTag] = empty
 					r.Rules["get"][modelFields.JsonTag] = "allow_get"
@@ -91,7 +133,7 @@ func deepResolve(r *RegisteredModel, m reflect.Type) {
 			}
 		}
 
-		// 获取gorm标签，检查当前字段是否是唯一或者有默认值等
+		// 获取gorm标签，获取当前字段的列名以及默认值等，唯一字段由 resolveUniqueFields 解析
 		if modelFields.GormTag != "" {
 			tags := strings.Split(modelFields.GormTag, ";")
 			for _, tag := range tags {
@@ -102,28 +144,12 @@ func deepResolve(r *RegisteredModel, m reflect.Type) {
 						modelFields.GormFieldName = columnName[1]
 					}
 				}
-				if strings.Contains(tag, "unique") {
-					modelFields.Unique = true
-				}
 				if strings.Contains(tag, "default") {
 					defaultValue := strings.Split(tag, ":")
 					if len(defaultValue) == 2 {
 						modelFields.Default = defaultValue[1]
 					}
 				}
-				if strings.Contains(tag, "foreignKey") {
-					if r.Associations == nil {
-						r.Associations = make(map[string]string)
-					}
-					foreignKey := strings.Split(tag, ":")
-					if len(foreignKey) == 2 {
-						if tableName, ok := strings.CutSuffix(foreignKey[1], "ID"); ok {
-							r.Associations[strcase.ToSnake(tableName)] = foreignKey[1]
-						} else {
-							panic("解析模型关联关系出错")
-						}
-					}
-				}
 			}
 		}
 		r.Fields = append(r.Fields, modelFields)
@@ -131,7 +157,64 @@ func deepResolve(r *RegisteredModel, m reflect.Type) {
 
 }
 
-func resolveModels() {
+// resolveAssociations 使用 GORM 的模型解析获取关联关系，支持 belongs_to、has_one、has_many、many2many 以及多态关联
+// 关联名称为关联字段的json标签，暂不支持复合外键的关联
+func resolveAssociations(r *RegisteredModel, s *schema.Schema) {
+	r.Associations = make(map[string]*association)
+	for _, rel := range s.Relationships.Relations {
+		name, _, _ := strings.Cut(rel.Field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = strcase.ToSnake(rel.Name)
+		}
+
+		a := &association{Type: string(rel.Type), Table: rel.FieldSchema.Table}
+		if rel.JoinTable != nil {
+			a.JoinTable = rel.JoinTable.Table
+		}
+		keys := 0
+		for _, ref := range rel.References {
+			// 多态关联中记录类型的条件
+			if ref.PrimaryValue != "" {
+				a.PolymorphicColumn, a.PolymorphicValue = ref.ForeignKey.DBName, ref.PrimaryValue
+				continue
+			}
+			keys++
+			switch rel.Type {
+			case schema.BelongsTo:
+				a.OwnColumn, a.RelatedColumn = ref.ForeignKey.DBName, ref.PrimaryKey.DBName
+			case schema.HasOne, schema.HasMany:
+				a.OwnColumn, a.RelatedColumn = ref.PrimaryKey.DBName, ref.ForeignKey.DBName
+			case schema.Many2Many:
+				if ref.OwnPrimaryKey {
+					a.OwnColumn, a.JoinOwnColumn = ref.PrimaryKey.DBName, ref.ForeignKey.DBName
+				} else {
+					a.RelatedColumn, a.JoinRelatedColumn = ref.PrimaryKey.DBName, ref.ForeignKey.DBName
+				}
+			}
+		}
+		if (a.JoinTable == "" && keys != 1) || (a.JoinTable != "" && keys != 2) {
+			continue
+		}
+		r.Associations[name] = a
+	}
+}
+
+// resolveUniqueFields 使用 GORM 的模型解析获取唯一字段，包括 unique 标签以及单列的唯一索引
+// 多列的唯一索引中的字段单独不是唯一的
+func resolveUniqueFields(r *RegisteredModel, s *schema.Schema) {
+	s.ParseIndexes()
+	for _, field := range r.Fields {
+		if f := s.LookUpField(field.Name); f != nil {
+			// 单列的 uniqueIndex 标签记录在 UniqueIndex 中，Unique 只对应 unique 标签
+			field.Unique = f.Unique || f.UniqueIndex != ""
+		}
+	}
+}
+
+func resolveModels(namer schema.Namer) {
 	for _, model := range collection {
 		// 解析模型元数据
 		m := reflect.TypeOf(model).Elem()
@@ -145,10 +228,27 @@ func resolveModels() {
 		}
 		// 深度解析
 		deepResolve(r, m)
+		resolveVersion(r)
+		s, err := schema.Parse(model, &sync.Map{}, namer)
+		if err != nil {
+			panic(fmt.Sprintf("解析模型 %s 出错: %v", r.ModelName, err))
+		}
+		resolveUniqueFields(r, s)
+		resolveAssociations(r, s)
 		registeredModels[model.TableName()] = r
 	}
 }
 
+// fieldByJsonTag 根据json标签查找字段信息
+func (r *RegisteredModel) fieldByJsonTag(jsonTag string) *Fields {
+	for _, field := range r.Fields {
+		if field.JsonTag == jsonTag {
+			return field
+		}
+	}
+	return nil
+}
+
 func getModelMeta(modelName string) *RegisteredModel {
 	return registeredModels[modelName]
 }
@@ -0,0 +1,49 @@
+package crud
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type uniqueIndexDoc struct {
+	ID      uint64 `gorm:"column:id;primary_key" json:"id"`
+	Slug    string `gorm:"column:slug;type:varchar(50);uniqueIndex" json:"slug" crud:"required_on_create,allow_get"`
+	Code    string `gorm:"column:code;type:varchar(50);unique" json:"code" crud:"allow_get"`
+	Owner   uint64 `gorm:"column:owner;uniqueIndex:idx_owner_title" json:"owner" crud:"allow_get"`
+	Title   string `gorm:"column:title;uniqueIndex:idx_owner_title" json:"title" crud:"allow_get,partial_update"`
+	Comment string `gorm:"column:comment" json:"comment" crud:"allow_get,partial_update"`
+}
+
+func (d *uniqueIndexDoc) TableName() string { return "unique_index_doc" }
+
+func TestResolveUniqueFields(t *testing.T) {
+	r, _ := newTestServer(t, &uniqueIndexDoc{})
+	RegisterModelApi[*uniqueIndexDoc](r.Group("/api"), "doc")
+
+	// 单列的 uniqueIndex 与 unique 标签都是唯一字段，多列唯一索引中的字段单独不是唯一的
+	meta := getModelMeta("unique_index_doc")
+	for field, want := range map[string]bool{"slug": true, "code": true, "owner": false, "title": false, "comment": false} {
+		if got := meta.fieldByJsonTag(field).Unique; got != want {
+			t.Errorf("%s: unique = %v, want %v", field, got, want)
+		}
+	}
+
+	create := func(query, body string) int {
+		req := httptest.NewRequest(http.MethodPost, "/api/doc"+query, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+	if code := create("", `{"slug":"a","code":"1","owner":1,"title":"a"}`); code != http.StatusCreated {
+		t.Fatalf("create status = %d", code)
+	}
+	if code := create("", `{"slug":"a","code":"2","owner":1,"title":"b"}`); code != http.StatusConflict {
+		t.Errorf("duplicate slug status = %d, want %d", code, http.StatusConflict)
+	}
+	if code := create("?on_conflict=update&conflict_target=slug", `{"slug":"a","code":"1","owner":1,"title":"c"}`); code >= http.StatusBadRequest {
+		t.Errorf("upsert on slug status = %d", code)
+	}
+}
@@ -0,0 +1,205 @@
+package crud
+
+import (
+	"errors"
+	"fmt"
+	"github.com/polaris0915/go-crud/cError"
+	"github.com/polaris0915/go-crud/model"
+	"github.com/spf13/cast"
+	"gorm.io/gorm"
+	"io"
+	"net/http"
+	"reflect"
+)
+
+// Restore 恢复软删除的记录，清空 deleted_at 并返回恢复之后的记录
+// 唯一字段与未删除的记录重复时不能恢复
+func (c *Core[T]) Restore() {
+	// 1. 解析路径参数，获取资源ID，嵌套路由检查父资源是否存在
+	if c.resolveParent(); c.err != nil {
+		return
+	}
+	id := c.resourceID()
+	if id == 0 {
+		c.err = cError.New(cError.ErrUpdateMissingField, nil, errors.New("缺少资源ID字段信息"))
+		return
+	}
+	modelMeta := c.restoreMeta()
+	if c.err != nil {
+		return
+	}
+
+	// 2. 查询已经被软删除的记录
+	record := c.getModel()
+	result := withDeleted(c.scoped(model.Use().Unscoped()), modelMeta, "", deletedOnly).First(&record, id)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			c.err = cError.New(cError.ErrUpdateNotFound, nil, fmt.Errorf("ID: %d的资源不存在或者没有被删除", id))
+		} else {
+			c.err = cError.New(cError.ErrDBQuery, nil, result.Error)
+		}
+		return
+	}
+	c.affectedIDs = []uint64{id}
+
+	// 3. 执行前置钩子
+	if c.beforeHook != nil {
+		if err := c.beforeHook(c); err != nil {
+			c.err = cError.New(cError.ErrUpdateHookFailure, nil, errors.New("恢复前置钩子函数执行失败"))
+			return
+		}
+	}
+
+	// 4. 在事务中检查唯一字段、清空 deleted_at 并执行后置钩子，后置钩子失败时回滚
+	err := model.Use().Transaction(func(tx *gorm.DB) error {
+		if c.restoreRecord(tx, modelMeta, record, id); c.err != nil {
+			return c.err
+		}
+		if c.afterHook != nil {
+			if err := c.afterHook(c); err != nil {
+				c.err = cError.New(cError.ErrUpdateHookFailure, nil, errors.New("恢复后置钩子函数执行失败"))
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		if c.err == nil {
+			c.err = cError.New(cError.ErrDBTransaction, nil, err)
+		}
+		return
+	}
+	invalidateModel(record.TableName())
+
+	// 5. 返回恢复之后的记录
+	restored, extraFields := c.readOne(modelMeta, id, nil, nil)
+	if c.err != nil {
+		return
+	}
+	for _, key := range extraFields {
+		delete(restored, key)
+	}
+	HandleRes(c.ginCtx, http.StatusOK, restored, "")
+}
+
+// BulkRestore 批量恢复软删除的记录，记录由请求体中的 ids 或者URL中的 ids 参数指定
+// 没有被删除或者不存在的记录会被忽略，任意一条记录的唯一字段重复时全部回滚
+func (c *Core[T]) BulkRestore() {
+	// 1. 嵌套路由检查父资源是否存在
+	if c.resolveParent(); c.err != nil {
+		return
+	}
+	modelMeta := c.restoreMeta()
+	if c.err != nil {
+		return
+	}
+
+	// 2. 绑定请求数据，请求体可以为空
+	var req bulkRequest
+	if err := c.ginCtx.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.err = cError.New(cError.ErrInvalidRequest, nil, errors.New("无效的请求数据格式"))
+		return
+	}
+	ids := req.IDs
+	if len(ids) == 0 {
+		if ids = c.queryIDs(); c.err != nil {
+			return
+		}
+	}
+	if len(ids) == 0 {
+		c.err = cError.New(cError.ErrUpdateMissingField, "必须指定 ids", errors.New("批量恢复缺少记录ID"))
+		return
+	}
+	if limit := c.maxAffected(); len(ids) > limit {
+		err := fmt.Errorf("恢复的记录数超过 %d", limit)
+		c.err = cError.New(cError.ErrUpdateTooMany, err.Error(), err)
+		return
+	}
+
+	// 3. 查询已经被软删除的记录
+	var records []T
+	db := withDeleted(c.scoped(model.Use().Unscoped()), modelMeta, "", deletedOnly)
+	if err := db.Where("id IN ?", ids).Order("id").Find(&records).Error; err != nil {
+		c.err = cError.New(cError.ErrDBQuery, nil, err)
+		return
+	}
+	result := &bulkResult{Matched: int64(len(records))}
+	for _, record := range records {
+		result.IDs = append(result.IDs, cast.ToUint64(modelID(record)))
+	}
+	c.affectedIDs = result.IDs
+	if result.Matched == 0 {
+		HandleRes(c.ginCtx, http.StatusOK, result, "")
+		return
+	}
+
+	// 4. 执行前置钩子
+	if c.beforeHook != nil {
+		if err := c.beforeHook(c); err != nil {
+			c.err = cError.New(cError.ErrUpdateHookFailure, nil, errors.New("恢复前置钩子函数执行失败"))
+			return
+		}
+	}
+
+	// 5. 在事务中逐条检查唯一字段并恢复，恢复之后的记录参与后面记录的检查，后置钩子失败时回滚
+	err := model.Use().Transaction(func(tx *gorm.DB) error {
+		for _, record := range records {
+			if c.restoreRecord(tx, modelMeta, record, cast.ToUint64(modelID(record))); c.err != nil {
+				return c.err
+			}
+			result.Affected++
+		}
+		if c.afterHook != nil {
+			if err := c.afterHook(c); err != nil {
+				c.err = cError.New(cError.ErrUpdateHookFailure, nil, errors.New("恢复后置钩子函数执行失败"))
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		if c.err == nil {
+			c.err = cError.New(cError.ErrDBTransaction, nil, err)
+		}
+		return
+	}
+	invalidateModel(c.getModel().TableName())
+
+	// 6. 返回结果
+	HandleRes(c.ginCtx, http.StatusOK, result, "")
+}
+
+// restoreMeta 获取模型元数据，模型没有 gorm.DeletedAt 字段时不能恢复
+func (c *Core[T]) restoreMeta() *RegisteredModel {
+	modelMeta := getModelMeta(c.getModel().TableName())
+	if modelMeta == nil || modelMeta.deletedAtField() == nil {
+		c.err = cError.New(cError.ErrInvalidRequest, "模型不支持软删除", errors.New("模型没有软删除字段"))
+		return nil
+	}
+	return modelMeta
+}
+
+// restoreRecord 检查记录的唯一字段是否与未删除的记录重复，不重复时清空 deleted_at
+// 记录删除之后可能已经创建了唯一字段相同的记录
+func (c *Core[T]) restoreRecord(db *gorm.DB, modelMeta *RegisteredModel, record T, id uint64) {
+	value := reflect.Indirect(reflect.ValueOf(record))
+	unique := make(map[string]interface{})
+	for _, field := range modelMeta.Fields {
+		if v := value.FieldByName(field.Name); field.Unique && v.IsValid() && !v.IsZero() {
+			unique[field.JsonTag] = v.Interface()
+		}
+	}
+	if _, err := findDuplicate(db, func() CModel { return c.getModel() }, unique); err != nil {
+		if errors.Is(err, errDataDuplicated) {
+			c.err = cError.New(cError.ErrCreateDuplicate, fmt.Sprintf("ID: %d的资源与未删除的记录重复", id), errDataDuplicated)
+		} else {
+			c.err = cError.New(cError.ErrDBQuery, nil, err)
+		}
+		return
+	}
+
+	column := modelMeta.deletedAtField().GormFieldName
+	if err := db.Unscoped().Model(c.getModel()).Where("id = ?", id).Update(column, nil).Error; err != nil {
+		c.err = cError.New(cError.ErrUpdateGeneral, nil, err)
+	}
+}
@@ -0,0 +1,99 @@
+package crud
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/polaris0915/go-crud/cError"
+	"gorm.io/gorm"
+)
+
+type trashUser struct {
+	ID        uint64         `gorm:"column:id;primary_key" json:"id" crud:"allow_get"`
+	Email     string         `gorm:"column:email;unique" json:"email" crud:"required_on_create,allow_get"`
+	DeletedAt gorm.DeletedAt `gorm:"column:deleted_at" json:"deleted_at"`
+}
+
+func (u *trashUser) TableName() string { return "trash_user" }
+
+func TestRestore(t *testing.T) {
+	// 软删除的表通常不在数据库中建立唯一索引，由接口检查唯一字段，表由测试自己创建
+	r, db := newTestServer(t)
+	if err := db.Exec("CREATE TABLE trash_user (id integer PRIMARY KEY, email text, deleted_at datetime)").Error; err != nil {
+		t.Fatal(err)
+	}
+	InitCrud(db, &trashUser{})
+	var restored [][]uint64
+	RegisterModelApi[*trashUser](r.Group("/api"), "user", BeforeRestore(func(c ICore) error {
+		restored = append(restored, c.GetAffectedIDs())
+		return nil
+	}))
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	errorCode := func(w *httptest.ResponseRecorder) int {
+		var resp struct {
+			Code int `json:"code"`
+		}
+		_ = json.Unmarshal(w.Body.Bytes(), &resp)
+		return resp.Code
+	}
+	for i, email := range []string{"a", "b", "c", "c"} {
+		db.Create(&trashUser{ID: uint64(i + 1), Email: email})
+	}
+	db.Delete(&trashUser{}, []uint64{1, 2, 3, 4})
+
+	t.Run("restore", func(t *testing.T) {
+		w := do(http.MethodPost, "/api/user/1/restore", "")
+		if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"email":"a"`) {
+			t.Fatalf("status = %d, body = %s", w.Code, w.Body.String())
+		}
+		if w := do(http.MethodGet, "/api/user/1", ""); w.Code != http.StatusOK {
+			t.Errorf("get restored status = %d", w.Code)
+		}
+		// 没有被删除的记录不能恢复
+		if w := do(http.MethodPost, "/api/user/1/restore", ""); w.Code != http.StatusNotFound {
+			t.Errorf("restore live record status = %d", w.Code)
+		}
+	})
+
+	t.Run("duplicate", func(t *testing.T) {
+		if w := do(http.MethodPost, "/api/user", `{"email":"b"}`); w.Code != http.StatusCreated {
+			t.Fatalf("create status = %d, body = %s", w.Code, w.Body.String())
+		}
+		if w := do(http.MethodPost, "/api/user/2/restore", ""); errorCode(w) != cError.ErrCreateDuplicate {
+			t.Errorf("status = %d, body = %s", w.Code, w.Body.String())
+		}
+	})
+
+	t.Run("bulk", func(t *testing.T) {
+		// 3、4 的 email 相同，恢复 3 之后 4 重复，全部回滚
+		if w := do(http.MethodPost, "/api/user/restore", `{"ids":[3,4]}`); errorCode(w) != cError.ErrCreateDuplicate {
+			t.Fatalf("status = %d, body = %s", w.Code, w.Body.String())
+		}
+		var live int64
+		db.Model(&trashUser{}).Where("id IN ?", []uint64{3, 4}).Count(&live)
+		if live != 0 {
+			t.Errorf("restored %d records after rollback", live)
+		}
+
+		restored = nil
+		w := do(http.MethodPost, "/api/user/restore?ids=1,3", "")
+		var resp struct {
+			Data bulkResult `json:"data"`
+		}
+		_ = json.Unmarshal(w.Body.Bytes(), &resp)
+		if w.Code != http.StatusOK || resp.Data.Matched != 1 || resp.Data.Affected != 1 || fmt.Sprint(restored) != "[[3]]" {
+			t.Errorf("status = %d, body = %s, hook ids = %v", w.Code, w.Body.String(), restored)
+		}
+	})
+}
@@ -11,7 +11,7 @@ func InitCrud(db *gorm.DB, models ...CModel) {
 	// 注册所有需要创建crud基本接口的模型
 	register(models...)
 	// 解析所有模型的元数据
-	resolveModels()
+	resolveModels(db.NamingStrategy)
 
 	// 初始化自定义验证器
 	initValidator()
@@ -21,30 +21,57 @@ func InitCrud(db *gorm.DB, models ...CModel) {
 
 func RegisterModelApi[T CModel](r *gin.RouterGroup, preSuffix string, opts ...Option) {
 	// 创建用户CRUD处理器
-	crud := newCrud(
-		// 模型工厂函数
-		func() T {
-			var m T
-			// 如果 T 是指针类型，确保它被初始化
-			modelType := reflect.TypeOf(m)
-			if modelType.Kind() == reflect.Ptr {
-				// 创建一个新的实例并返回其指针
-				modelValue := reflect.New(modelType.Elem())
-				return modelValue.Interface().(T)
-			}
-			return m
-		},
-		opts...,
-	)
+	crud := newCrud(newModel[T], opts...)
 
 	registerRoutes[T](r, preSuffix, crud)
 }
 
-// registerRoutes 注册CRUD路由
+// RegisterNestedModelApi 注册嵌套的子资源路由，例如 /user/:id/files、/user/:id/files/:child_id
+// relation 为父模型中子资源的关联名称（关联字段的json标签），同时作为子资源的路径，只支持 has_one、has_many 关联
+// 所有操作都会先检查父资源是否存在，查询和修改只作用于属于该父资源的子资源，创建时自动写入父资源的外键
+func RegisterNestedModelApi[P CModel, C CModel](r *gin.RouterGroup, parentPath, relation string, opts ...Option) {
+	crud := newCrud(newModel[C], opts...)
+	crud.parent = &nestedParent{
+		getModel: func() CModel { return newModel[P]() },
+		Relation: relation,
+	}
+
+	// gin 要求同一位置的路径参数名称相同，父资源ID沿用父资源路由中的 :id
+	registerRoutes[C](r, parentPath+"/:id/"+relation, crud)
+}
+
+// newModel 模型工厂函数
+func newModel[T CModel]() T {
+	var m T
+	// 如果 T 是指针类型，确保它被初始化
+	modelType := reflect.TypeOf(m)
+	if modelType.Kind() == reflect.Ptr {
+		// 创建一个新的实例并返回其指针
+		modelValue := reflect.New(modelType.Elem())
+		return modelValue.Interface().(T)
+	}
+	return m
+}
+
+// registerRoutes 注册CRUD路由，嵌套路由中子资源的ID参数为 :child_id
 func registerRoutes[T CModel](group *gin.RouterGroup, preSuffix string, crud *Crud[T]) {
+	idParam := ":id"
+	if crud.parent != nil {
+		idParam = ":child_id"
+	}
 	group.POST("/"+preSuffix, crud.Create()...)
-	group.DELETE("/"+preSuffix+"/:id", crud.Delete()...)
-	group.PATCH("/"+preSuffix+"/:id", crud.Update()...)
-	group.GET("/"+preSuffix+"/:id", crud.Get()...)
+	group.POST("/"+preSuffix+"/batch", crud.CreateBatch()...)
+	group.DELETE("/"+preSuffix+"/"+idParam, crud.Delete()...)
+	group.DELETE("/"+preSuffix, crud.BulkDelete()...)
+	group.PATCH("/"+preSuffix, crud.BulkUpdate()...)
+	group.PATCH("/"+preSuffix+"/"+idParam, crud.Update()...)
+	group.PUT("/"+preSuffix+"/"+idParam, crud.Replace()...)
+	group.GET("/"+preSuffix+"/"+idParam, crud.Get()...)
 	group.GET("/"+preSuffix+"", crud.GetList()...)
+	group.POST("/"+preSuffix+"/search", crud.Search()...)
+	group.POST("/"+preSuffix+"/import", crud.Import()...)
+	group.GET("/"+preSuffix+"/aggregate", crud.Aggregate()...)
+	group.GET("/"+preSuffix+"/export", crud.Export()...)
+	group.POST("/"+preSuffix+"/restore", crud.BulkRestore()...)
+	group.POST("/"+preSuffix+"/"+idParam+"/restore", crud.Restore()...)
 }
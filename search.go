@@ -0,0 +1,198 @@
+package crud
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/polaris0915/go-crud/cError"
+	"strings"
+)
+
+// searchRequest POST /{resource}/search 的请求体
+//
+//	{
+//	  "where": {"and": [
+//	    {"or": [{"field": "status", "op": "eq", "value": "open"}, {"field": "assignee", "value": 42}]},
+//	    {"not": {"field": "type", "op": "in", "value": ["a", "b"]}}
+//	  ]},
+//	  "fields": ["id", "status"],
+//	  "expand": ["relate_type"],
+//	  "sort": ["-created_at", "id"],
+//	  "page": 1,
+//	  "per_page": 20
+//	}
+type searchRequest struct {
+	Where  *searchWhere `json:"where"`
+	Fields []string     `json:"fields"`
+	Expand []string     `json:"expand"`
+	// Sort 排序字段列表，格式与 GetList 的 sort 参数相同，例如 ["-created_at", "closed_at:nulls_last"]
+	Sort    []string `json:"sort"`
+	Page    int      `json:"page"`
+	PerPage int      `json:"per_page"`
+	// Cursor 与 Limit 任意一个存在时使用游标分页，第一页可以传入 "cursor": ""
+	Cursor *string `json:"cursor"`
+	Limit  int     `json:"limit"`
+	// Count 统计总记录数的方式 exact、estimate、none
+	Count string `json:"count"`
+	// IncludeDeleted 同时返回软删除的记录，OnlyDeleted 只返回软删除的记录，需要 TrashAccess 权限
+	IncludeDeleted bool `json:"include_deleted"`
+	OnlyDeleted    bool `json:"only_deleted"`
+}
+
+// searchWhere 查询条件节点
+// 每个节点只能是 and、or、not 或者字段条件中的一种
+type searchWhere struct {
+	And []*searchWhere `json:"and"`
+	Or  []*searchWhere `json:"or"`
+	Not *searchWhere   `json:"not"`
+
+	Field string `json:"field"`
+	// Op 过滤操作符，与查询参数中的前缀相同，例如 eq、gte、in、between，默认为 eq
+	Op string `json:"op"`
+	// Value 条件的值，in、nin、between 操作符使用数组
+	Value interface{} `json:"value"`
+}
+
+// toFilterExpr 将查询条件节点转换为过滤表达式
+func (w *searchWhere) toFilterExpr() (*filterExpr, error) {
+	kinds := 0
+	for _, set := range []bool{w.And != nil, w.Or != nil, w.Not != nil, w.Field != ""} {
+		if set {
+			kinds++
+		}
+	}
+	if kinds != 1 {
+		return nil, errors.New("每个查询条件节点只能包含 and、or、not、field 中的一个")
+	}
+
+	switch {
+	case w.And != nil, w.Or != nil:
+		expr := &filterExpr{Logic: logicAnd}
+		children := w.And
+		if w.Or != nil {
+			expr.Logic = logicOr
+			children = w.Or
+		}
+		if len(children) == 0 {
+			return nil, fmt.Errorf("%s 条件不能为空", expr.Logic)
+		}
+		for _, child := range children {
+			if child == nil {
+				return nil, fmt.Errorf("%s 条件中包含空节点", expr.Logic)
+			}
+			childExpr, err := child.toFilterExpr()
+			if err != nil {
+				return nil, err
+			}
+			expr.Children = append(expr.Children, childExpr)
+		}
+		return expr, nil
+
+	case w.Not != nil:
+		child, err := w.Not.toFilterExpr()
+		if err != nil {
+			return nil, err
+		}
+		return &filterExpr{Logic: logicNot, Children: []*filterExpr{child}}, nil
+	}
+
+	op := strings.ToLower(w.Op)
+	if op == "" {
+		op = opEq
+	}
+	if alias, ok := filterExprOperators[op]; ok {
+		op = alias
+	}
+	if _, ok := filterOperators[op]; !ok {
+		return nil, fmt.Errorf("字段 %s 使用了不支持的操作符 %s", w.Field, w.Op)
+	}
+
+	cond := &filterCondition{Field: w.Field, Operator: op}
+	switch value := w.Value.(type) {
+	case nil:
+		// {"field": "x", "value": null} 等价于 is null
+		switch op {
+		case opEq, opIs:
+			cond.Operator, cond.Values = opIs, []interface{}{"null"}
+		case opNe:
+			cond.Operator, cond.Values = opIs, []interface{}{"notnull"}
+		default:
+			return nil, fmt.Errorf("字段 %s 缺少条件的值", w.Field)
+		}
+	case []interface{}:
+		cond.Values = value
+	default:
+		cond.Values = []interface{}{value}
+	}
+	return &filterExpr{Cond: cond}, nil
+}
+
+// Search 使用请求体中的查询条件执行列表查询
+// 与 GetList 使用相同的字段检查、钩子以及响应格式
+func (c *Core[T]) Search() {
+	ctx := c.ginCtx
+
+	// 1. 读取请求体，请求体为空时等价于不带参数的列表查询
+	body, err := ctx.GetRawData()
+	if err != nil {
+		c.err = cError.New(cError.ErrInvalidRequest, nil, err)
+		return
+	}
+	var req searchRequest
+	if len(bytes.TrimSpace(body)) > 0 {
+		// 请求体只解析一次，使用 UseNumber 避免大整数ID在转换为float64时丢失精度，钩子函数中可以通过 GetPayload 获取
+		decoder := json.NewDecoder(bytes.NewReader(body))
+		decoder.UseNumber()
+		if err := decoder.Decode(&c.payload); err != nil {
+			c.err = cError.New(cError.ErrInvalidRequest, "请求体不是有效的查询条件", err)
+			return
+		}
+		if err := weakDecode(c.payload, &req); err != nil {
+			c.err = cError.New(cError.ErrInvalidRequest, "请求体不是有效的查询条件", err)
+			return
+		}
+	}
+
+	// 2. 组织列表查询参数
+	q := &listQuery{
+		Page:    req.Page,
+		PerPage: req.PerPage,
+		Fields:  req.Fields,
+		Count:   req.Count,
+	}
+	if req.Cursor != nil || req.Limit > 0 {
+		q.CursorMode, q.Limit = true, req.Limit
+		if req.Cursor != nil {
+			q.Cursor = *req.Cursor
+		}
+	}
+	q.normalizePage()
+	if q.Deleted = c.deletedMode(req.IncludeDeleted, req.OnlyDeleted); c.err != nil {
+		return
+	}
+	expand, err := parseExpand(strings.Join(req.Expand, ","))
+	if err != nil {
+		c.err = cError.New(cError.ErrReadExpand, err.Error(), err)
+		return
+	}
+	q.Expand = expand
+	for _, s := range req.Sort {
+		sort, err := parseSortField(s)
+		if err != nil {
+			c.err = cError.New(cError.ErrReadSort, err.Error(), err)
+			return
+		}
+		q.Sort = append(q.Sort, sort)
+	}
+	if req.Where != nil {
+		expr, err := req.Where.toFilterExpr()
+		if err != nil {
+			c.err = cError.New(cError.ErrReadFilter, err.Error(), err)
+			return
+		}
+		q.Where = expr
+	}
+
+	c.list(q)
+}
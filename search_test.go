@@ -0,0 +1,117 @@
+package crud
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/polaris0915/go-crud/cError"
+)
+
+type searchTask struct {
+	ID       uint64  `gorm:"column:id;primary_key" json:"id" crud:"allow_get"`
+	Status   string  `gorm:"column:status" json:"status" crud:"allow_get"`
+	Assignee *uint64 `gorm:"column:assignee" json:"assignee" crud:"allow_get"`
+	Secret   string  `gorm:"column:secret" json:"secret"`
+}
+
+func (s *searchTask) TableName() string { return "search_task" }
+
+func TestSearch(t *testing.T) {
+	r, db := newTestServer(t, &searchTask{})
+	RegisterModelApi[*searchTask](r.Group("/api"), "task")
+
+	assignee := func(id uint64) *uint64 { return &id }
+	for _, task := range []searchTask{
+		{ID: 1, Status: "open", Assignee: assignee(42)},
+		{ID: 2, Status: "open"},
+		{ID: 3, Status: "closed", Assignee: assignee(42)},
+		{ID: 4, Status: "closed"},
+		{ID: 5, Status: "review", Assignee: assignee(7)},
+	} {
+		db.Create(&task)
+	}
+
+	type searchResponse struct {
+		Code int `json:"code"`
+		Data struct {
+			Data []struct {
+				ID uint64 `json:"id"`
+			} `json:"data"`
+			Pagination struct {
+				CurrentPage int    `json:"current_page"`
+				NextCursor  string `json:"next_cursor"`
+			} `json:"pagination"`
+		} `json:"data"`
+	}
+	search := func(t *testing.T, body string) (searchResponse, []uint64) {
+		t.Helper()
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/task/search", strings.NewReader(body)))
+		var resp searchResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("%s: %v", body, err)
+		}
+		var ids []uint64
+		for _, item := range resp.Data.Data {
+			ids = append(ids, item.ID)
+		}
+		return resp, ids
+	}
+
+	t.Run("where", func(t *testing.T) {
+		tests := []struct {
+			body string
+			want string
+		}{
+			{`{"where":{"and":[{"or":[{"field":"status","value":"open"},{"field":"assignee","value":42}]},{"not":{"field":"id","op":"in","value":[1]}}]},"sort":["id"]}`, "[2 3]"},
+			{`{"where":{"field":"assignee","value":null},"sort":["id"]}`, "[2 4]"},
+			{`{"where":{"field":"assignee","op":"ne","value":null},"sort":["id"]}`, "[1 3 5]"},
+			{`{"where":{"field":"status","op":"in","value":["review","closed"]},"sort":["-id"]}`, "[5 4 3]"},
+			{``, "[1 2 3 4 5]"},
+		}
+		for _, tt := range tests {
+			if resp, ids := search(t, tt.body); resp.Code != http.StatusOK || fmt.Sprint(ids) != tt.want {
+				t.Errorf("%s: code = %d, ids = %v, want %s", tt.body, resp.Code, ids, tt.want)
+			}
+		}
+	})
+
+	t.Run("pagination", func(t *testing.T) {
+		resp, ids := search(t, `{"sort":["-id"],"page":2,"per_page":2,"fields":["id"]}`)
+		if fmt.Sprint(ids) != "[3 2]" || resp.Data.Pagination.CurrentPage != 2 {
+			t.Errorf("page 2 = %v, pagination = %+v", ids, resp.Data.Pagination)
+		}
+
+		resp, ids = search(t, `{"sort":["id"],"limit":2,"fields":["id"]}`)
+		if fmt.Sprint(ids) != "[1 2]" || resp.Data.Pagination.NextCursor == "" {
+			t.Fatalf("first cursor page = %v, pagination = %+v", ids, resp.Data.Pagination)
+		}
+		_, ids = search(t, fmt.Sprintf(`{"sort":["id"],"limit":2,"fields":["id"],"cursor":%q}`, resp.Data.Pagination.NextCursor))
+		if fmt.Sprint(ids) != "[3 4]" {
+			t.Errorf("second cursor page = %v", ids)
+		}
+	})
+
+	t.Run("rejected", func(t *testing.T) {
+		tests := []struct {
+			body string
+			code int
+		}{
+			{`{"where":{"field":"secret","value":"x"}}`, cError.ErrReadFilter},
+			{`{"fields":["secret"]}`, cError.ErrReadInvalidField},
+			{`{"sort":["secret"]}`, cError.ErrReadSort},
+			{`{"where":{"field":"status","value":"open","and":[]}}`, cError.ErrReadFilter},
+			{`{"where":{"field":"status","op":"gt","value":null}}`, cError.ErrReadFilter},
+			{`[1]`, cError.ErrInvalidRequest},
+		}
+		for _, tt := range tests {
+			if resp, _ := search(t, tt.body); resp.Code != tt.code {
+				t.Errorf("%s: code = %d, want %d", tt.body, resp.Code, tt.code)
+			}
+		}
+	})
+}
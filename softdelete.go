@@ -0,0 +1,81 @@
+package crud
+
+import (
+	"errors"
+	"fmt"
+	"github.com/polaris0915/go-crud/cError"
+	"gorm.io/gorm"
+	"strconv"
+)
+
+// 软删除记录的查询方式，默认不返回软删除的记录
+const (
+	deletedExclude = ""
+	deletedInclude = "include"
+	deletedOnly    = "only"
+)
+
+// deletedAtField 模型中 gorm.DeletedAt 类型的软删除字段，没有时返回空
+func (r *RegisteredModel) deletedAtField() *Fields {
+	for _, field := range r.Fields {
+		if field.Type == deletedAtType {
+			return field
+		}
+	}
+	return nil
+}
+
+// withDeleted 按照查询方式为 Table 查询添加软删除条件，Table 查询不会自动添加 GORM 的软删除条件
+// table 不为空时使用表名限定列名，用于连接查询
+func withDeleted(db *gorm.DB, modelMeta *RegisteredModel, table, mode string) *gorm.DB {
+	field := modelMeta.deletedAtField()
+	if field == nil || mode == deletedInclude {
+		return db
+	}
+	column := field.GormFieldName
+	if table != "" {
+		column = table + "." + column
+	}
+	if mode == deletedOnly {
+		return db.Where(column + " IS NOT NULL")
+	}
+	return db.Where(column + " IS NULL")
+}
+
+// deletedQuery 从URL查询参数 include_deleted、only_deleted 中解析软删除记录的查询方式
+func (c *Core[T]) deletedQuery() string {
+	var flags [2]bool
+	for i, name := range []string{"include_deleted", "only_deleted"} {
+		value, ok := c.ginCtx.GetQuery(name)
+		if !ok || value == "" {
+			continue
+		}
+		flag, err := strconv.ParseBool(value)
+		if err != nil {
+			c.err = cError.New(cError.ErrReadFilter, fmt.Sprintf("%s 只能是 true 或 false", name), err)
+			return deletedExclude
+		}
+		flags[i] = flag
+	}
+	return c.deletedMode(flags[0], flags[1])
+}
+
+// deletedMode 检查查询软删除记录的权限，没有配置 TrashAccess 或者检查不通过时不能查询软删除的记录
+func (c *Core[T]) deletedMode(include, only bool) string {
+	if include && only {
+		err := errors.New("include_deleted 与 only_deleted 不能同时使用")
+		c.err = cError.New(cError.ErrReadFilter, err.Error(), err)
+		return deletedExclude
+	}
+	if !include && !only {
+		return deletedExclude
+	}
+	if c.config == nil || c.config.TrashAccess == nil || !c.config.TrashAccess(c.ginCtx) {
+		c.err = cError.New(cError.ErrReadPermission, "无权查看已删除的记录", errors.New("没有查看已删除记录的权限"))
+		return deletedExclude
+	}
+	if only {
+		return deletedOnly
+	}
+	return deletedInclude
+}
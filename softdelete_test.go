@@ -0,0 +1,96 @@
+package crud
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/polaris0915/go-crud/model"
+)
+
+func TestSoftDeleteReads(t *testing.T) {
+	r, db := newTestServer(t, &model.RelateType{}, &model.File{})
+	for i := 1; i <= 2; i++ {
+		db.Create(&model.RelateType{ID: uint64(i), Type: fmt.Sprintf("type%d", i)})
+	}
+	for i := 1; i <= 3; i++ {
+		db.Create(&model.File{ID: uint64(i), FileName: fmt.Sprintf("f%d", i), FilePath: fmt.Sprintf("/f%d", i), RelateTypeID: uint64(i%2 + 1)})
+	}
+	db.Delete(&model.File{}, 3)
+	db.Delete(&model.RelateType{}, 1)
+
+	admin := TrashAccess(func(ctx *gin.Context) bool { return ctx.GetHeader("X-Admin") == "1" })
+	RegisterModelApi[*model.File](r.Group("/api"), "file", admin)
+
+	do := func(method, path string, isAdmin bool) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		if isAdmin {
+			req.Header.Set("X-Admin", "1")
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	names := func(t *testing.T, path string) []string {
+		t.Helper()
+		w := do(http.MethodGet, path, true)
+		var resp struct {
+			Data struct {
+				Data []struct {
+					FileName string `json:"file_name"`
+				} `json:"data"`
+				Pagination model.Pagination `json:"pagination"`
+			} `json:"data"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || w.Code != http.StatusOK {
+			t.Fatalf("GET %s: %d %s", path, w.Code, w.Body.String())
+		}
+		var result []string
+		for _, item := range resp.Data.Data {
+			result = append(result, item.FileName)
+		}
+		if total := resp.Data.Pagination.Total; int(total) != len(result) {
+			t.Errorf("GET %s: total = %v, ids = %v", path, total, result)
+		}
+		return result
+	}
+
+	t.Run("default", func(t *testing.T) {
+		if w := do(http.MethodGet, "/api/file/3", false); w.Code != http.StatusNotFound {
+			t.Errorf("get deleted status = %d", w.Code)
+		}
+		if got := names(t, "/api/file?fields=file_name&sort=file_name"); fmt.Sprint(got) != "[f1 f2]" {
+			t.Errorf("list = %v", got)
+		}
+		// 软删除的关联记录不展开
+		w := do(http.MethodGet, "/api/file/2?expand=relate_type", false)
+		var resp struct {
+			Data map[string]interface{} `json:"data"`
+		}
+		_ = json.Unmarshal(w.Body.Bytes(), &resp)
+		if w.Code != http.StatusOK || resp.Data["relate_type"] != nil {
+			t.Errorf("expand deleted relation: %d %s", w.Code, w.Body.String())
+		}
+	})
+
+	t.Run("trash", func(t *testing.T) {
+		if w := do(http.MethodGet, "/api/file?include_deleted=true", false); w.Code != http.StatusForbidden {
+			t.Errorf("without permission status = %d", w.Code)
+		}
+		if w := do(http.MethodGet, "/api/file?include_deleted=true&only_deleted=true", true); w.Code != http.StatusBadRequest {
+			t.Errorf("both flags status = %d", w.Code)
+		}
+		if w := do(http.MethodGet, "/api/file/3?include_deleted=true", true); w.Code != http.StatusOK {
+			t.Errorf("get deleted status = %d", w.Code)
+		}
+		if got := names(t, "/api/file?fields=file_name&sort=file_name&include_deleted=true"); fmt.Sprint(got) != "[f1 f2 f3]" {
+			t.Errorf("include_deleted = %v", got)
+		}
+		if got := names(t, "/api/file?fields=file_name&sort=file_name&only_deleted=1"); fmt.Sprint(got) != "[f3]" {
+			t.Errorf("only_deleted = %v", got)
+		}
+	})
+}
@@ -0,0 +1,77 @@
+package crud
+
+import (
+	"fmt"
+	"strings"
+)
+
+// 空值排序位置
+const (
+	nullsFirst = "nulls_first"
+	nullsLast  = "nulls_last"
+)
+
+// sortField 排序字段
+type sortField struct {
+	Field string
+	Desc  bool
+	// Nulls 空值排在最前（nulls_first）还是最后（nulls_last），为空时由数据库决定
+	Nulls string
+}
+
+// parseSortField 解析单个排序字段
+// - 前缀表示降序，+ 前缀或者没有前缀表示升序，:nulls_first / :nulls_last 后缀指定空值的位置
+// 例如 -created_at、display_name、-closed_at:nulls_last
+func parseSortField(s string) (sortField, error) {
+	s = strings.TrimSpace(s)
+	var field sortField
+
+	if name, nulls, ok := strings.Cut(s, ":"); ok {
+		nulls = strings.ToLower(strings.TrimSpace(nulls))
+		if nulls != nullsFirst && nulls != nullsLast {
+			return field, fmt.Errorf("排序字段 %s 的空值位置只能是 %s 或 %s", name, nullsFirst, nullsLast)
+		}
+		s, field.Nulls = strings.TrimSpace(name), nulls
+	}
+
+	if name, ok := strings.CutPrefix(s, "-"); ok {
+		field.Field, field.Desc = name, true
+	} else {
+		field.Field = strings.TrimPrefix(s, "+")
+	}
+	if field.Field == "" {
+		return field, fmt.Errorf("排序字段不能为空")
+	}
+	return field, nil
+}
+
+// parseSortParam 解析逗号分隔的多列排序参数，例如 -created_at,display_name
+func parseSortParam(param string) ([]sortField, error) {
+	var fields []sortField
+	for _, item := range splitParam(param) {
+		field, err := parseSortField(item)
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// orderClauses 生成排序语句
+// 不是所有数据库都支持 NULLS FIRST/LAST，这里使用 CASE 表达式实现空值的位置
+func (s sortField) orderClauses() []string {
+	direction := "asc"
+	if s.Desc {
+		direction = "desc"
+	}
+
+	var clauses []string
+	switch s.Nulls {
+	case nullsFirst:
+		clauses = append(clauses, fmt.Sprintf("CASE WHEN %s IS NULL THEN 0 ELSE 1 END", s.Field))
+	case nullsLast:
+		clauses = append(clauses, fmt.Sprintf("CASE WHEN %s IS NULL THEN 1 ELSE 0 END", s.Field))
+	}
+	return append(clauses, fmt.Sprintf("%s %s", s.Field, direction))
+}
@@ -0,0 +1,146 @@
+package crud
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/polaris0915/go-crud/cError"
+)
+
+func TestParseSortParam(t *testing.T) {
+	tests := []struct {
+		param string
+		want  []sortField
+	}{
+		{"", nil},
+		{"id", []sortField{{Field: "id"}}},
+		{"+id", []sortField{{Field: "id"}}},
+		{"-created_at, display_name", []sortField{{Field: "created_at", Desc: true}, {Field: "display_name"}}},
+		{"-closed_at:nulls_last,id:NULLS_FIRST", []sortField{
+			{Field: "closed_at", Desc: true, Nulls: nullsLast},
+			{Field: "id", Nulls: nullsFirst},
+		}},
+	}
+	for _, tt := range tests {
+		got, err := parseSortParam(tt.param)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", tt.param, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q: got %+v, want %+v", tt.param, got, tt.want)
+		}
+	}
+
+	for _, param := range []string{"-", "id,+", "closed_at:nulls", ":nulls_first"} {
+		if _, err := parseSortParam(param); err == nil {
+			t.Errorf("%q: expected error", param)
+		}
+	}
+}
+
+func TestOrderClauses(t *testing.T) {
+	tests := []struct {
+		field sortField
+		want  []string
+	}{
+		{sortField{Field: "id"}, []string{"id asc"}},
+		{sortField{Field: "id", Desc: true}, []string{"id desc"}},
+		{sortField{Field: "closed_at", Nulls: nullsFirst}, []string{"CASE WHEN closed_at IS NULL THEN 0 ELSE 1 END", "closed_at asc"}},
+		{sortField{Field: "closed_at", Desc: true, Nulls: nullsLast}, []string{"CASE WHEN closed_at IS NULL THEN 1 ELSE 0 END", "closed_at desc"}},
+	}
+	for _, tt := range tests {
+		if got := tt.field.orderClauses(); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%+v: got %q, want %q", tt.field, got, tt.want)
+		}
+	}
+}
+
+func TestResolveSorts(t *testing.T) {
+	meta := newFilterTestMeta()
+	tests := []struct {
+		defaultSort string
+		sorts       []sortField
+		want        []sortField
+		code        int
+	}{
+		{"", nil, nil, 0},
+		{"-created_at,id", nil, []sortField{{Field: "created_at", Desc: true}, {Field: "id"}}, 0},
+		// 请求中指定了排序时不使用默认排序
+		{"-created_at", []sortField{{Field: "status"}}, []sortField{{Field: "status"}}, 0},
+		{"", []sortField{{Field: "secret"}}, nil, cError.ErrReadSort},
+		{"-secret", nil, nil, cError.ErrReadSort},
+		{"id:nulls", nil, nil, cError.ErrInvalidConfig},
+	}
+	for _, tt := range tests {
+		c := &Core[*filterTestModel]{config: &Config{DefaultSort: tt.defaultSort}}
+		got := c.resolveSorts(meta, tt.sorts)
+		if tt.code != 0 {
+			if c.err == nil || c.err.Code != tt.code {
+				t.Errorf("default %q, sorts %+v: err = %v, want code %d", tt.defaultSort, tt.sorts, c.err, tt.code)
+			}
+			continue
+		}
+		if c.err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("default %q, sorts %+v: got %+v, err = %v, want %+v", tt.defaultSort, tt.sorts, got, c.err, tt.want)
+		}
+	}
+}
+
+type sortTask struct {
+	ID       uint64  `gorm:"column:id;primary_key" json:"id" crud:"allow_get"`
+	Priority int     `gorm:"column:priority" json:"priority" crud:"allow_get"`
+	DueAt    *string `gorm:"column:due_at" json:"due_at" crud:"allow_get"`
+}
+
+func (s *sortTask) TableName() string { return "sort_task" }
+
+func TestListSort(t *testing.T) {
+	r, db := newTestServer(t, &sortTask{})
+	RegisterModelApi[*sortTask](r.Group("/api"), "task", DefaultSort("-priority,id"))
+
+	due := func(s string) *string { return &s }
+	for _, task := range []sortTask{
+		{ID: 1, Priority: 1, DueAt: due("2026-03-01")},
+		{ID: 2, Priority: 2},
+		{ID: 3, Priority: 1},
+		{ID: 4, Priority: 2, DueAt: due("2026-01-01")},
+	} {
+		db.Create(&task)
+	}
+
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"", "[2 4 1 3]"},
+		{"?sort=priority,-id", "[3 1 4 2]"},
+		{"?sort=due_at:nulls_first,id", "[2 3 4 1]"},
+		{"?sort=-due_at:nulls_last,id", "[1 4 2 3]"},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/task"+tt.query, nil))
+		var resp struct {
+			Data struct {
+				Data []struct {
+					ID uint64 `json:"id"`
+				} `json:"data"`
+			} `json:"data"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("%s: %v", tt.query, err)
+		}
+		var ids []uint64
+		for _, item := range resp.Data.Data {
+			ids = append(ids, item.ID)
+		}
+		if fmt.Sprint(ids) != tt.want {
+			t.Errorf("%s: got %v, want %s", tt.query, ids, tt.want)
+		}
+	}
+}
@@ -5,16 +5,28 @@ import (
 	"fmt"
 	"github.com/polaris0915/go-crud/cError"
 	"github.com/polaris0915/go-crud/model"
-	"github.com/spf13/cast"
 	"gorm.io/gorm"
 	"net/http"
 )
 
 // Update 执行部分更新操作（PATCH）
 // TODO 注意事项 在编写更新操作的钩子函数的时候，传入进去的是map[string]interface{}
+// 请求体根据 Content-Type 可以是普通的 JSON 对象、JSON Patch（RFC 6902）或者 JSON Merge Patch（RFC 7396）
 func (c *Core[T]) Update() {
-	// 1. 解析路径参数（获取资源 ID）
-	id := cast.ToUint64(c.ginCtx.Param("id"))
+	c.update(c.bindPatch, c.checkPartialUpdate)
+}
+
+// update 更新操作的通用流程
+// bind 根据已有的记录解析请求数据，check 检查请求数据中的字段，可以补全请求数据
+func (c *Core[T]) update(
+	bind func(existing T) map[string]interface{},
+	check func(modelMeta *RegisteredModel, data map[string]interface{}),
+) {
+	// 1. 解析路径参数（获取资源 ID），嵌套路由检查父资源是否存在
+	if c.resolveParent(); c.err != nil {
+		return
+	}
+	id := c.resourceID()
 	if id == 0 {
 		c.err = cError.New(cError.ErrUpdateMissingField, nil, errors.New("缺少资源ID字段信息"))
 		return
@@ -22,7 +34,7 @@ func (c *Core[T]) Update() {
 
 	// 2. 检查资源是否存在
 	existingModel := c.getModel()
-	result := model.Use().Where("id = ?", id).First(&existingModel)
+	result := c.scoped(model.Use()).Where("id = ?", id).First(&existingModel)
 	if result.Error != nil {
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
 			c.err = cError.New(cError.ErrUpdateNotFound, nil, fmt.Errorf("ID: %d的资源不存在", id))
@@ -31,42 +43,25 @@ func (c *Core[T]) Update() {
 		}
 		return
 	}
-
-	// 3. 绑定请求数据
-	jsonMap := map[string]interface{}{}
-	if err := c.ginCtx.ShouldBindJSON(&jsonMap); err != nil {
-		c.err = cError.New(cError.ErrUpdateInvalidField, nil, errors.New("无效的请求数据格式"))
-		return
-	}
-
-	// 如果请求体为空，返回错误
-	if len(jsonMap) == 0 {
-		c.err = cError.New(cError.ErrUpdateMissingField, nil, errors.New("请求体不能为空"))
-		return
-	}
-
-	// 4. 检查请求数据中的字段是否都支持正常的部分更新操作
-	// 获取支持部分更新的字段map
+	// 检查 If-Match 请求头与记录当前的版本是否一致
 	modelMeta := getModelMeta(existingModel.TableName())
-	if modelMeta == nil {
-		c.err = cError.New(cError.ErrUpdateGeneral, nil, fmt.Errorf("无法获取模型为%s的元数据", existingModel.TableName()))
+	version := c.checkIfMatch(modelMeta, existingModel)
+	if c.err != nil {
 		return
 	}
 
-	partialUpdateFields := modelMeta.PartialUpdateFields
-	if len(partialUpdateFields) == 0 { // 该模型不支持字段更新
-		c.err = cError.New(cError.ErrUpdateInvalidField, nil, errors.New("该模型不支持部分更新"))
+	// 3. 绑定请求数据
+	jsonMap := bind(existingModel)
+	if c.err != nil {
 		return
 	}
 
-	//validFieldsMap := make(map[string]interface{})
-	for field := range jsonMap {
-		_, ok := modelMeta.PartialUpdateFields[field]
-		if !ok {
-			c.err = cError.New(cError.ErrUpdateInvalidField, nil, fmt.Errorf("字段 '%s' 不支持更新操作", field))
-			return
-		}
+	// 4. 检查请求数据中的字段是否都支持更新操作
+	if check(modelMeta, jsonMap); c.err != nil {
+		return
 	}
+	// 嵌套路由中子资源不能修改为其他父资源
+	c.injectScope(jsonMap)
 
 	// 5. 权限检查会在中间件中进行处理
 
@@ -114,19 +109,29 @@ func (c *Core[T]) Update() {
 		defer func() {
 			if c.err != nil {
 				tx.Rollback()
-			} else {
-				tx.Commit()
 			}
 		}()
 	}
 
 	// 执行更新操作
 	// 将用户在钩子函数中操作完之后的jsonModel拿过去更新
-	result = tx.Model(existingModel).Where("id = ?", id).Updates(jsonMap)
+	// 模型有版本字段时只更新版本没有变化的记录
+	if version != nil {
+		bumpVersion(jsonMap, modelMeta)
+	}
+	result = versionCondition(tx.Model(existingModel).Where("id = ?", id), modelMeta, version).Updates(jsonMap)
 	if result.Error != nil {
 		c.err = cError.New(cError.ErrUpdateGeneral, nil, result.Error)
 		return
 	}
+	if version != nil && result.RowsAffected == 0 {
+		c.err = c.currentVersionConflict(modelMeta, tx, id)
+		return
+	}
+	// 开启事务时在提交之后使缓存失效
+	if !c.enableTransaction {
+		invalidateModel(existingModel.TableName())
+	}
 
 	if result.RowsAffected == 0 {
 		// TODO 如果这里需要告诉用户字段没有发生变化怎么编写响应信息合适？
@@ -147,6 +152,57 @@ func (c *Core[T]) Update() {
 		}
 	}
 
-	// 8. 返回结果
+	// 提交事务，在提交之后使缓存失效，避免并发的查询在提交之前读到旧数据并重新写入缓存
+	if c.enableTransaction {
+		if err := tx.Commit().Error; err != nil {
+			c.err = cError.New(cError.ErrDBTransaction, nil, err)
+			return
+		}
+		invalidateModel(existingModel.TableName())
+	}
+
+	// 8. 返回结果，ETag 为更新后的版本
+	if version := modelVersion(modelMeta, updatedModel); version != nil {
+		c.ginCtx.Header("ETag", versionETag(version))
+	}
 	HandleRes(c.ginCtx, http.StatusOK, updatedModel, "")
 }
+
+// bindJSON 绑定普通的 JSON 对象请求数据
+func (c *Core[T]) bindJSON(T) map[string]interface{} {
+	jsonMap := map[string]interface{}{}
+	if err := c.ginCtx.ShouldBindJSON(&jsonMap); err != nil {
+		c.err = cError.New(cError.ErrUpdateInvalidField, nil, errors.New("无效的请求数据格式"))
+		return nil
+	}
+	return jsonMap
+}
+
+// checkPartialUpdate 检查请求数据不为空，并且所有字段都是允许部分更新的字段
+func (c *Core[T]) checkPartialUpdate(modelMeta *RegisteredModel, data map[string]interface{}) {
+	// 如果请求体为空，返回错误
+	if len(data) == 0 {
+		c.err = cError.New(cError.ErrUpdateMissingField, nil, errors.New("请求体不能为空"))
+		return
+	}
+
+	// 获取支持部分更新的字段map
+	if modelMeta == nil {
+		c.err = cError.New(cError.ErrUpdateGeneral, nil, fmt.Errorf("无法获取模型为%s的元数据", c.getModel().TableName()))
+		return
+	}
+
+	partialUpdateFields := modelMeta.PartialUpdateFields
+	if len(partialUpdateFields) == 0 { // 该模型不支持字段更新
+		c.err = cError.New(cError.ErrUpdateInvalidField, nil, errors.New("该模型不支持部分更新"))
+		return
+	}
+
+	for field := range data {
+		_, ok := partialUpdateFields[field]
+		if !ok {
+			c.err = cError.New(cError.ErrUpdateInvalidField, nil, fmt.Errorf("字段 '%s' 不支持更新操作", field))
+			return
+		}
+	}
+}
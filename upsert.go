@@ -0,0 +1,161 @@
+package crud
+
+import (
+	"errors"
+	"fmt"
+	"github.com/polaris0915/go-crud/cError"
+	"github.com/polaris0915/go-crud/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// upsertClause 生成创建时唯一字段冲突的处理子句，GORM 会根据数据库生成 ON CONFLICT 或者 ON DUPLICATE KEY UPDATE
+// 冲突字段为 conflict_target 参数指定的字段，没有指定时为请求数据中的唯一字段，两者都只能有一个字段
+// on_conflict=update 时只更新请求数据中允许部分更新的字段，on_conflict=skip 时不做任何操作
+// MySQL 中任意唯一索引冲突都会触发更新，conflict_target 只用于检查
+// 更新已有记录时仍然执行创建钩子，并且不检查 If-Match，带有 version 标签的版本字段加1
+// existing 表示冲突的记录已经存在，此时会更新或者跳过该记录而不是创建
+func (c *Core[T]) upsertClause(onConflict string) (conflict *clause.OnConflict, existing bool) {
+	modelMeta := getModelMeta(c.getModel().TableName())
+
+	// 1. 获取冲突字段
+	// 每个唯一字段有各自的唯一索引，PostgreSQL、SQLite 要求 ON CONFLICT 的字段对应同一个唯一索引，因此只能有一个冲突字段
+	var target *Fields
+	if param := c.ginCtx.Query("conflict_target"); param != "" {
+		jsonTags := splitParam(param)
+		if len(jsonTags) != 1 {
+			c.err = cError.New(cError.ErrCreateInvalidField, "conflict_target 只能指定一个唯一字段", nil)
+			return nil, false
+		}
+		target = modelMeta.fieldByJsonTag(jsonTags[0])
+		if target == nil || target.GormFieldName == "" {
+			c.err = cError.New(cError.ErrCreateInvalidField, fmt.Sprintf("conflict_target 中的字段 %s 不存在", jsonTags[0]), nil)
+			return nil, false
+		}
+		// PostgreSQL、SQLite 要求冲突字段上有唯一索引，否则执行时报错
+		if !target.Unique {
+			c.err = cError.New(cError.ErrCreateInvalidField, fmt.Sprintf("conflict_target 中的字段 %s 不是唯一字段", jsonTags[0]), nil)
+			return nil, false
+		}
+		if _, ok := c.payload[target.JsonTag]; !ok {
+			c.err = cError.New(cError.ErrCreateMissingField, fmt.Sprintf("请求数据中缺少冲突字段 %s", target.JsonTag), nil)
+			return nil, false
+		}
+	} else {
+		for _, field := range modelMeta.Fields {
+			if _, ok := c.payload[field.JsonTag]; !ok || !field.Unique {
+				continue
+			}
+			if target != nil {
+				c.err = cError.New(cError.ErrCreateValidation, "请求数据中有多个唯一字段，需要使用 conflict_target 指定冲突字段", errors.New("冲突字段不唯一"))
+				return nil, false
+			}
+			target = field
+		}
+	}
+	if target == nil {
+		c.err = cError.New(cError.ErrCreateValidation, "请求数据中没有可以用于判断冲突的唯一字段", errors.New("缺少冲突字段"))
+		return nil, false
+	}
+
+	// 2. 冲突的记录已经被软删除或者不属于当前父资源时不允许更新或者跳过
+	if existing = c.checkUpsertConflict(modelMeta, target); c.err != nil {
+		return nil, false
+	}
+
+	// 3. 生成更新的字段，冲突字段本身不更新
+	conflict = &clause.OnConflict{Columns: []clause.Column{{Name: target.GormFieldName}}}
+	var updates []string
+	if onConflict == conflictUpdate {
+		for _, field := range modelMeta.Fields {
+			if _, ok := modelMeta.PartialUpdateFields[field.JsonTag]; !ok {
+				continue
+			}
+			if _, ok := c.payload[field.JsonTag]; ok && field != target {
+				updates = append(updates, field.GormFieldName)
+			}
+		}
+		// 有更新时同时更新 updated_at
+		for _, field := range modelMeta.Fields {
+			if len(updates) > 0 && field.GormFieldName == "updated_at" {
+				updates = append(updates, field.GormFieldName)
+			}
+		}
+	}
+	if len(updates) == 0 {
+		conflict.DoNothing = true
+		return conflict, existing
+	}
+	conflict.DoUpdates = clause.AssignmentColumns(updates)
+	// 带有 version 标签的版本字段加1，之前获取的 ETag 失效，使用表名限定已有记录的版本字段
+	if modelMeta.Version != nil && modelMeta.RequireIfMatch {
+		column := modelMeta.Version.GormFieldName
+		conflict.DoUpdates = append(conflict.DoUpdates, clause.Assignment{
+			Column: clause.Column{Name: column},
+			Value:  gorm.Expr(fmt.Sprintf("%s.%s + 1", c.getModel().TableName(), column)),
+		})
+	}
+	return conflict, existing
+}
+
+// checkUpsertConflict 检查与请求数据冲突的已有记录，返回冲突的记录是否存在
+// 软删除的记录仍然占用唯一索引，数据库会更新已经删除的记录，需要先恢复该记录；嵌套路由中记录还必须属于当前父资源
+func (c *Core[T]) checkUpsertConflict(modelMeta *RegisteredModel, target *Fields) bool {
+	var ids []uint64
+	err := model.Use().Unscoped().Model(c.getModel()).
+		Where(fmt.Sprintf("%s = ?", target.GormFieldName), c.payload[target.JsonTag]).
+		Limit(1).Pluck("id", &ids).Error
+	if err != nil {
+		c.err = cError.New(cError.ErrDBQuery, nil, err)
+		return false
+	}
+	if len(ids) == 0 {
+		return false
+	}
+	if modelMeta.deletedAtField() != nil {
+		var count int64
+		if err := model.Use().Model(c.getModel()).Where("id = ?", ids[0]).Count(&count).Error; err != nil {
+			c.err = cError.New(cError.ErrDBQuery, nil, err)
+			return false
+		}
+		if count == 0 {
+			c.err = cError.New(cError.ErrCreateDuplicate, "与已删除的记录冲突，需要先恢复该记录", errDataDuplicated)
+			return false
+		}
+	}
+	if c.scope == nil {
+		return true
+	}
+	var count int64
+	if err := c.scoped(model.Use().Model(c.getModel())).Where("id = ?", ids[0]).Count(&count).Error; err != nil {
+		c.err = cError.New(cError.ErrDBQuery, nil, err)
+		return false
+	}
+	if count == 0 {
+		c.err = cError.New(cError.ErrCreateDuplicate, nil, errDataDuplicated)
+	}
+	return true
+}
+
+// conflictID 使用冲突字段查询创建、更新或者跳过的记录ID
+// 数据库处理冲突时不区分软删除的记录，这里同样包括软删除的记录
+func (c *Core[T]) conflictID(conflict *clause.OnConflict) uint64 {
+	modelMeta := getModelMeta(c.getModel().TableName())
+	db := c.scoped(model.Use().Unscoped().Model(c.getModel()))
+	for _, column := range conflict.Columns {
+		for _, field := range modelMeta.Fields {
+			if field.GormFieldName == column.Name {
+				db = db.Where(fmt.Sprintf("%s = ?", column.Name), c.payload[field.JsonTag])
+			}
+		}
+	}
+	var ids []uint64
+	if err := db.Limit(1).Pluck("id", &ids).Error; err != nil {
+		c.err = cError.New(cError.ErrDBQuery, nil, err)
+		return 0
+	}
+	if len(ids) == 0 {
+		return 0
+	}
+	return ids[0]
+}
@@ -0,0 +1,138 @@
+package crud
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+func TestCreateUpsert(t *testing.T) {
+	r, db := newTestServer(t, &importUser{})
+	RegisterModelApi[*importUser](r.Group("/api"), "user")
+
+	create := func(query, body string) int {
+		req := httptest.NewRequest(http.MethodPost, "/api/user?"+query, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+	users := func() []string {
+		var rows []importUser
+		db.Order("id").Find(&rows)
+		var result []string
+		for _, row := range rows {
+			result = append(result, fmt.Sprintf("%s:%s:%d", row.Email, row.Name, row.Age))
+		}
+		return result
+	}
+
+	if code := create("", `{"email":"a@x.com","name":"A","age":20}`); code != http.StatusCreated {
+		t.Fatalf("create status = %d", code)
+	}
+	if code := create("", `{"email":"a@x.com","name":"A2"}`); code != http.StatusConflict {
+		t.Errorf("duplicate status = %d", code)
+	}
+
+	// 只更新请求数据中允许部分更新的字段，age 不在请求数据中保持不变
+	if code := create("on_conflict=update", `{"email":"a@x.com","name":"A2"}`); code != http.StatusOK {
+		t.Errorf("upsert status = %d", code)
+	}
+	if code := create("on_conflict=update&conflict_target=email", `{"email":"b@x.com","name":"B"}`); code != http.StatusCreated {
+		t.Errorf("upsert insert status = %d", code)
+	}
+	if code := create("on_conflict=skip", `{"email":"b@x.com","name":"B2","age":9}`); code != http.StatusOK {
+		t.Errorf("skip status = %d", code)
+	}
+	if want := []string{"a@x.com:A2:20", "b@x.com:B:0"}; !reflect.DeepEqual(users(), want) {
+		t.Errorf("users = %v, want %v", users(), want)
+	}
+
+	// name 不是唯一字段，不能作为冲突字段；冲突字段只能有一个
+	for _, query := range []string{
+		"on_conflict=replace", "on_conflict=update&conflict_target=nope", "on_conflict=update&conflict_target=name",
+		"on_conflict=update&conflict_target=email,email",
+	} {
+		if code := create(query, `{"email":"c@x.com","name":"C"}`); code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d", query, code)
+		}
+	}
+}
+
+type upsertDoc struct {
+	ID        uint64         `gorm:"column:id;primary_key" json:"id" crud:"allow_get"`
+	Slug      string         `gorm:"column:slug;unique" json:"slug" crud:"required_on_create,allow_get"`
+	Title     string         `gorm:"column:title" json:"title" crud:"allow_get,partial_update"`
+	Version   int            `gorm:"column:version;default:1" json:"version" crud:"allow_get,version"`
+	DeletedAt gorm.DeletedAt `gorm:"column:deleted_at" json:"deleted_at"`
+}
+
+func (d *upsertDoc) TableName() string { return "upsert_doc" }
+
+func TestCreateUpsertExisting(t *testing.T) {
+	r, db := newTestServer(t, &upsertDoc{})
+	RegisterModelApi[*upsertDoc](r.Group("/api"), "doc")
+
+	upsert := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/doc?on_conflict=update", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	db.Create(&upsertDoc{ID: 1, Slug: "a", Title: "A"})
+	db.Create(&upsertDoc{ID: 2, Slug: "b", Title: "B"})
+
+	// 更新已有记录时返回 200 以及该记录，没有 Location，版本字段加1
+	w := upsert(`{"slug":"a","title":"A2"}`)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"title":"A2","version":2`) ||
+		w.Header().Get("Location") != "" {
+		t.Errorf("status = %d, location = %s, body = %s", w.Code, w.Header().Get("Location"), w.Body.String())
+	}
+
+	// 与软删除的记录冲突时不更新
+	db.Delete(&upsertDoc{}, 2)
+	if w := upsert(`{"slug":"b","title":"B2"}`); w.Code != http.StatusConflict {
+		t.Errorf("trashed: status = %d, body = %s", w.Code, w.Body.String())
+	}
+	var doc upsertDoc
+	db.Unscoped().First(&doc, 2)
+	if doc.Title != "B" || doc.Version != 1 {
+		t.Errorf("trashed record updated: %+v", doc)
+	}
+}
+
+func TestCreateUpsertTarget(t *testing.T) {
+	r, _ := newTestServer(t, &uniqueIndexDoc{})
+	RegisterModelApi[*uniqueIndexDoc](r.Group("/api"), "doc")
+
+	upsert := func(query, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/doc?on_conflict=update"+query, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	// 新建记录时返回 201 以及 Location
+	w := upsert("&conflict_target=slug", `{"slug":"a","code":"1","owner":1,"title":"a"}`)
+	if w.Code != http.StatusCreated || w.Header().Get("Location") != "/api/doc/1" {
+		t.Errorf("insert: status = %d, location = %q", w.Code, w.Header().Get("Location"))
+	}
+	// slug 与 code 各自有唯一索引，没有指定 conflict_target 时无法确定冲突字段
+	if w := upsert("", `{"slug":"a","code":"1","title":"b"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("two unique fields: status = %d, body = %s", w.Code, w.Body.String())
+	}
+	if w := upsert("&conflict_target=slug,code", `{"slug":"a","code":"1","title":"b"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("two targets: status = %d, body = %s", w.Code, w.Body.String())
+	}
+	w = upsert("&conflict_target=slug", `{"slug":"a","code":"1","title":"b"}`)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"title":"b"`) {
+		t.Errorf("update: status = %d, body = %s", w.Code, w.Body.String())
+	}
+}
@@ -5,7 +5,9 @@ import (
 	"fmt"
 	"github.com/mitchellh/mapstructure"
 	"github.com/polaris0915/go-crud/model"
+	"github.com/spf13/cast"
 	"gorm.io/gorm"
+	"strings"
 )
 
 var (
@@ -16,33 +18,39 @@ var (
 
 // checkUniqueness 检查当前请求数据中的唯一属性的字段是否已经在数据库中存在了
 func checkUniqueness(getModel func() CModel, payload map[string]interface{}) error {
+	_, err := findDuplicate(model.Use(), getModel, payload)
+	return err
+}
+
+// findDuplicate 查找唯一字段的值与请求数据相同的记录，存在时返回该记录的ID以及 errDataDuplicated
+// 请求数据中没有的唯一字段不检查，在事务中检查时 db 为事务，可以查到事务中已经创建的记录
+func findDuplicate(db *gorm.DB, getModel func() CModel, payload map[string]interface{}) (uint64, error) {
 	// 获取需要检查的模型
-	// TODO 这边得验证这个jsonModel是值传递还是引用传递，如果是引用传递下面的First就会出问题
 	jsonModel := getModel()
 	// 获取模型元数据
 	modelMeta := getModelMeta(jsonModel.TableName())
 
 	for _, field := range modelMeta.Fields {
-		if field.Unique {
-			var err error
-			val := payload[field.Name]
-			if err =
-				model.Use().Model(jsonModel).Where(fmt.Sprintf("%s = ?", field.GormFieldName), val).
-					Limit(1).First(jsonModel).Error; err == nil {
-				// 数据已经被创建过
-				// TODO 输出日志
-				return errDataDuplicated
-			}
-			// 数据没有被创建过
-			if errors.Is(err, gorm.ErrRecordNotFound) {
-				return nil
-			}
-
-			//// TODO 输出日志
-			return errUnknown
+		if !field.Unique {
+			continue
+		}
+		val, ok := payload[field.JsonTag]
+		if !ok || val == nil {
+			continue
+		}
+		var row map[string]interface{}
+		err := db.Model(jsonModel).Select("id").Where(fmt.Sprintf("%s = ?", field.GormFieldName), val).
+			Limit(1).Find(&row).Error
+		if err != nil {
+			// TODO 输出日志
+			return 0, errUnknown
+		}
+		// 数据已经被创建过
+		if len(row) > 0 {
+			return cast.ToUint64(row["id"]), errDataDuplicated
 		}
 	}
-	return nil
+	return 0, nil
 }
 
 // weakDecode decodes the input data to the output data with weakly typed input
@@ -62,3 +70,14 @@ func weakDecode(input, output interface{}) error {
 
 	return decoder.Decode(input)
 }
+
+// splitParam 将逗号分隔的参数拆分为列表，忽略空白项
+func splitParam(param string) []string {
+	var items []string
+	for _, item := range strings.Split(param, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,123 @@
+package crud
+
+import (
+	"errors"
+	"fmt"
+	"github.com/polaris0915/go-crud/cError"
+	"gorm.io/gorm"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// resolveVersion 没有 version 标签的字段时使用 updated_at 作为版本字段，此时 If-Match 请求头是可选的
+func resolveVersion(r *RegisteredModel) {
+	if r.Version == nil {
+		r.Version = r.updatedAtField()
+	}
+}
+
+// formatVersion 将版本字段的值转换为 ETag 中的字符串，时间使用 UTC 的 RFC3339 格式
+func formatVersion(version interface{}) string {
+	switch v := version.(type) {
+	case time.Time:
+		return v.UTC().Format(time.RFC3339Nano)
+	case *time.Time:
+		if v != nil {
+			return v.UTC().Format(time.RFC3339Nano)
+		}
+		return ""
+	case []byte:
+		return string(v)
+	}
+	return fmt.Sprint(version)
+}
+
+// versionETag 版本对应的 ETag
+func versionETag(version interface{}) string {
+	return `"` + formatVersion(version) + `"`
+}
+
+// etagMatch 检查 If-Match 或者 If-None-Match 请求头中是否包含 etag，* 匹配所有的 etag
+// weak 为 false 时使用强比较（If-Match），弱 ETag 不与任何 ETag 匹配；为 true 时使用弱比较（If-None-Match），忽略 W/ 前缀
+func etagMatch(header, etag string, weak bool) bool {
+	if weak {
+		etag = strings.TrimPrefix(etag, "W/")
+	} else if strings.HasPrefix(etag, "W/") {
+		return false
+	}
+	for _, item := range strings.Split(header, ",") {
+		item = strings.TrimSpace(item)
+		if weak {
+			item = strings.TrimPrefix(item, "W/")
+		}
+		if item == "*" || item == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// modelVersion 获取模型中版本字段的值，模型没有版本字段时返回空
+func modelVersion(modelMeta *RegisteredModel, m CModel) interface{} {
+	if modelMeta == nil || modelMeta.Version == nil {
+		return nil
+	}
+	return reflect.Indirect(reflect.ValueOf(m)).FieldByName(modelMeta.Version.Name).Interface()
+}
+
+// checkIfMatch 检查 If-Match 请求头与记录当前的版本是否一致，返回用于条件更新、删除的版本
+// 模型没有版本字段或者请求没有 If-Match 请求头时返回空，模型带有 version 标签时必须带有 If-Match 请求头
+func (c *Core[T]) checkIfMatch(modelMeta *RegisteredModel, existing T) interface{} {
+	version := modelVersion(modelMeta, existing)
+	if version == nil {
+		return nil
+	}
+	header := c.ginCtx.GetHeader("If-Match")
+	if header == "" {
+		if modelMeta.RequireIfMatch {
+			c.err = cError.New(cError.ErrPrecondition, nil, errors.New("缺少 If-Match 请求头"))
+		}
+		return nil
+	}
+	if !etagMatch(header, versionETag(version), false) {
+		c.err = c.versionConflict(formatVersion(version))
+	}
+	return version
+}
+
+// versionCondition 在更新、删除中添加版本条件，记录已经被修改时不会影响任何记录
+func versionCondition(db *gorm.DB, modelMeta *RegisteredModel, version interface{}) *gorm.DB {
+	if version == nil {
+		return db
+	}
+	return db.Where(fmt.Sprintf("%s = ?", modelMeta.Version.GormFieldName), version)
+}
+
+// bumpVersion 带有 version 标签的整数版本字段在更新时加1，updated_at 由 GORM 自动更新
+func bumpVersion(data map[string]interface{}, modelMeta *RegisteredModel) {
+	if modelMeta.Version == nil || !modelMeta.RequireIfMatch {
+		return
+	}
+	column := modelMeta.Version.GormFieldName
+	data[column] = gorm.Expr(column + " + 1")
+}
+
+// currentVersionConflict 条件更新、删除没有影响任何记录时，查询记录当前的版本并返回并发更新冲突
+func (c *Core[T]) currentVersionConflict(modelMeta *RegisteredModel, db *gorm.DB, id uint64) *cError.Error {
+	latest := c.getModel()
+	err := db.Select(modelMeta.Version.GormFieldName).Where("id = ?", id).Take(latest).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// 记录已经被删除
+		return c.versionConflict("")
+	}
+	if err != nil {
+		return cError.New(cError.ErrDBQuery, nil, err)
+	}
+	return c.versionConflict(formatVersion(modelVersion(modelMeta, latest)))
+}
+
+// versionConflict 并发更新冲突，Detail 中为记录当前的版本
+func (c *Core[T]) versionConflict(current string) *cError.Error {
+	return cError.New(cError.ErrUpdateConcurrency, map[string]interface{}{"version": current}, errors.New("记录已经被修改"))
+}
@@ -0,0 +1,161 @@
+package crud
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/polaris0915/go-crud/cError"
+	"gorm.io/gorm"
+)
+
+type versionDoc struct {
+	ID      uint64 `gorm:"column:id;primary_key" json:"id"`
+	Title   string `gorm:"column:title" json:"title" crud:"allow_get,partial_update"`
+	Version int    `gorm:"column:version;default:1" json:"version" crud:"version"`
+}
+
+func (d *versionDoc) TableName() string { return "version_doc" }
+
+type versionNote struct {
+	ID        uint64    `gorm:"column:id;primary_key" json:"id"`
+	Body      string    `gorm:"column:body" json:"body" crud:"allow_get,partial_update"`
+	UpdatedAt time.Time `gorm:"column:updated_at" json:"updated_at" crud:"allow_get"`
+}
+
+func (n *versionNote) TableName() string { return "version_note" }
+
+func TestOptimisticConcurrency(t *testing.T) {
+	r, db := newTestServer(t, &versionDoc{}, &versionNote{})
+	RegisterModelApi[*versionDoc](r.Group("/api"), "doc")
+	RegisterModelApi[*versionNote](r.Group("/api"), "note")
+
+	do := func(method, path, ifMatch, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	errorCode := func(w *httptest.ResponseRecorder) (int, interface{}) {
+		var resp struct {
+			Code   int         `json:"code"`
+			Detail interface{} `json:"detail"`
+		}
+		_ = json.Unmarshal(w.Body.Bytes(), &resp)
+		return resp.Code, resp.Detail
+	}
+
+	t.Run("version tag", func(t *testing.T) {
+		db.Create(&versionDoc{ID: 1, Title: "a"})
+		if etag := do(http.MethodGet, "/api/doc/1", "", "").Header().Get("ETag"); etag != `"1"` {
+			t.Fatalf("etag = %q", etag)
+		}
+
+		// 带有 version 标签时必须带有 If-Match
+		if w := do(http.MethodPatch, "/api/doc/1", "", `{"title":"b"}`); w.Code != http.StatusPreconditionRequired {
+			t.Errorf("missing If-Match status = %d", w.Code)
+		}
+		w := do(http.MethodPatch, "/api/doc/1", `"1"`, `{"title":"b"}`)
+		if w.Code != http.StatusOK || w.Header().Get("ETag") != `"2"` {
+			t.Fatalf("status = %d, etag = %q", w.Code, w.Header().Get("ETag"))
+		}
+
+		// 使用旧的版本更新或者删除时返回当前的版本
+		w = do(http.MethodPatch, "/api/doc/1", `"1"`, `{"title":"c"}`)
+		if code, detail := errorCode(w); code != cError.ErrUpdateConcurrency || fmt.Sprint(detail) != "map[version:2]" {
+			t.Errorf("stale update = %d %v", code, detail)
+		}
+		if w := do(http.MethodDelete, "/api/doc/1", `"1"`, ""); w.Code != http.StatusConflict {
+			t.Errorf("stale delete status = %d", w.Code)
+		}
+		// If-Match 使用强比较，弱 ETag 不匹配
+		if w := do(http.MethodDelete, "/api/doc/1", `W/"2"`, ""); w.Code != http.StatusConflict {
+			t.Errorf("weak If-Match delete status = %d", w.Code)
+		}
+		if w := do(http.MethodDelete, "/api/doc/1", `"3", "2"`, ""); w.Code != http.StatusNoContent {
+			t.Errorf("delete status = %d", w.Code)
+		}
+	})
+
+	t.Run("updated_at", func(t *testing.T) {
+		db.Create(&versionNote{ID: 1, Body: "a"})
+		// 没有 version 标签时 If-Match 是可选的
+		if w := do(http.MethodPatch, "/api/note/1", "", `{"body":"b"}`); w.Code != http.StatusOK {
+			t.Fatalf("status = %d", w.Code)
+		}
+		etag := do(http.MethodGet, "/api/note/1", "", "").Header().Get("ETag")
+		if etag == "" {
+			t.Fatal("missing etag")
+		}
+		w := do(http.MethodPatch, "/api/note/1", etag, `{"body":"c"}`)
+		if w.Code != http.StatusOK || w.Header().Get("ETag") == etag {
+			t.Fatalf("status = %d, etag = %q", w.Code, w.Header().Get("ETag"))
+		}
+		if w := do(http.MethodPatch, "/api/note/1", etag, `{"body":"d"}`); w.Code != http.StatusConflict {
+			t.Errorf("stale update status = %d", w.Code)
+		}
+	})
+
+	t.Run("without If-Match", func(t *testing.T) {
+		// 没有 If-Match 请求头时更新、删除不添加版本条件
+		var conditions []string
+		record := func(tx *gorm.DB) {
+			sql := tx.Statement.SQL.String()
+			if i := strings.Index(sql, "WHERE"); i >= 0 {
+				conditions = append(conditions, sql[i:])
+			}
+		}
+		if err := db.Callback().Update().After("gorm:update").Register("test:update_where", record); err != nil {
+			t.Fatal(err)
+		}
+		if err := db.Callback().Delete().After("gorm:delete").Register("test:delete_where", record); err != nil {
+			t.Fatal(err)
+		}
+		db.Create(&versionNote{ID: 2, Body: "a"})
+		if w := do(http.MethodPatch, "/api/note/2", "", `{"body":"b"}`); w.Code != http.StatusOK {
+			t.Fatalf("update status = %d", w.Code)
+		}
+		if w := do(http.MethodDelete, "/api/note/2", "", ""); w.Code != http.StatusNoContent {
+			t.Fatalf("delete status = %d", w.Code)
+		}
+		if len(conditions) != 2 {
+			t.Fatalf("conditions = %q", conditions)
+		}
+		for _, where := range conditions {
+			if strings.Contains(where, "updated_at") {
+				t.Errorf("unexpected version condition: %s", where)
+			}
+		}
+	})
+}
+
+func TestETagMatch(t *testing.T) {
+	tests := []struct {
+		header, etag string
+		weak, want   bool
+	}{
+		{`"1"`, `"1"`, false, true},
+		{`"2", "1"`, `"1"`, false, true},
+		{`*`, `"1"`, false, true},
+		{`W/"1"`, `"1"`, false, false},
+		{`"1"`, `W/"1"`, false, false},
+		{`"2"`, `"1"`, false, false},
+		{`W/"1"`, `"1"`, true, true},
+		{`"1"`, `W/"1"`, true, true},
+		{`W/"2", W/"1"`, `W/"1"`, true, true},
+		{`"2"`, `"1"`, true, false},
+	}
+	for _, tt := range tests {
+		if got := etagMatch(tt.header, tt.etag, tt.weak); got != tt.want {
+			t.Errorf("etagMatch(%s, %s, %v) = %v, want %v", tt.header, tt.etag, tt.weak, got, tt.want)
+		}
+	}
+}